@@ -6,10 +6,11 @@ import (
 )
 
 type MyConfig struct {
-	Port       int    `json:"port"`
-	Host       string `json:"host"`
-	UsedDB     string `json:"used_db"`
-	PostgreSQL struct {
+	Port        int    `json:"port"`
+	Host        string `json:"host"`
+	UsedDB      string `json:"used_db"`
+	AutoMigrate bool   `json:"auto_migrate"`
+	PostgreSQL  struct {
 		Port        int    `json:"port"`
 		Host        string `json:"host"`
 		Username    string `json:"username"`
@@ -18,6 +19,60 @@ type MyConfig struct {
 		SSLMode     string `json:"ssl_mode"`
 		SSLRootCert string `json:"ssl_root_cert"`
 	} `json:"postgresql"`
+	SQLite struct {
+		Path string `json:"path"`
+	} `json:"sqlite"`
+	Bolt struct {
+		Path string `json:"path"`
+	} `json:"bolt"`
+	SoftDelete struct {
+		RetentionHours       int `json:"retention_hours"`
+		PurgeIntervalMinutes int `json:"purge_interval_minutes"`
+	} `json:"soft_delete"`
+	Timeouts struct {
+		RequestMs int            `json:"request_ms"`
+		QueryMs   int            `json:"query_ms"`
+		RoutesMs  map[string]int `json:"routes_ms"`
+	} `json:"timeouts"`
+	Shutdown struct {
+		GracePeriodSeconds int `json:"grace_period_seconds"`
+	} `json:"shutdown"`
+	RequestBody struct {
+		MaxBytes int64 `json:"max_bytes"`
+	} `json:"request_body"`
+	Batch struct {
+		MaxAds int `json:"max_ads"`
+	} `json:"batch"`
+	Idempotency struct {
+		TTLHours int `json:"ttl_hours"`
+	} `json:"idempotency"`
+	Validation struct {
+		TitleMaxLength       int   `json:"title_max_length"`
+		DescriptionMaxLength int   `json:"description_max_length"`
+		PhotoLinksMax        int   `json:"photo_links_max"`
+		PriceMax             int64 `json:"price_max"`
+	} `json:"validation"`
+	Log struct {
+		Level  string `json:"level"`
+		Format string `json:"format"`
+	} `json:"log"`
+	Tracing struct {
+		Exporter    string `json:"exporter"`
+		Endpoint    string `json:"endpoint"`
+		File        string `json:"file"`
+		ServiceName string `json:"service_name"`
+	} `json:"tracing"`
+	Auth struct {
+		JWTSecret    string            `json:"jwt_secret"`
+		JWTIssuer    string            `json:"jwt_issuer"`
+		APIKeys      map[string]APIKey `json:"api_keys"`
+		PublicRoutes []string          `json:"public_routes"`
+	} `json:"auth"`
+}
+
+type APIKey struct {
+	UserID string   `json:"user_id"`
+	Roles  []string `json:"roles"`
 }
 
 func LoadConfig(filename string) (MyConfig, error) {
@@ -1,13 +1,16 @@
 module adv-backend-trainee-assignment
 
-go 1.15
+go 1.16
 
 require (
-	github.com/google/uuid v1.2.0
+	github.com/google/uuid v1.3.0
 	github.com/gorilla/mux v1.8.0
+	github.com/jackc/pgconn v1.8.0
 	github.com/jackc/pgx/v4 v4.10.1
-	github.com/pkg/errors v0.9.1 // indirect
+	github.com/prometheus/client_golang v1.9.0
 	github.com/sirupsen/logrus v1.7.0
 	github.com/stretchr/testify v1.5.1
+	go.etcd.io/bbolt v1.3.6
 	golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad // indirect
+	modernc.org/sqlite v1.17.3
 )
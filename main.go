@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"adv-backend-trainee-assignment/config"
+	"adv-backend-trainee-assignment/src/auth"
 	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/logging"
+	"adv-backend-trainee-assignment/src/metrics"
 	"adv-backend-trainee-assignment/src/routes"
+	"adv-backend-trainee-assignment/src/tracing"
+	"adv-backend-trainee-assignment/src/validation"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 )
@@ -20,28 +29,139 @@ func Middleware(h http.Handler) http.Handler {
 	})
 }
 
-func newRouter(cfg config.MyConfig) *mux.Router {
-	r := mux.NewRouter()
-	s := r.PathPrefix("/api/v1").Subrouter()
-	var server routes.APIServer
+func timeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			h.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func routeTimeout(cfg config.MyConfig, routeName string) time.Duration {
+	if timeoutMs, ok := cfg.Timeouts.RoutesMs[routeName]; ok {
+		return time.Duration(timeoutMs) * time.Millisecond
+	}
+	return time.Duration(cfg.Timeouts.RequestMs) * time.Millisecond
+}
+
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+func postgreSQLUrl(cfg config.MyConfig) string {
+	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s", cfg.PostgreSQL.Username, cfg.PostgreSQL.Password, cfg.PostgreSQL.Host, cfg.PostgreSQL.Port, cfg.PostgreSQL.DBName, cfg.PostgreSQL.SSLMode)
+}
+
+func newDBManager(cfg config.MyConfig) db.DatabaseConnection {
+	var dbManager db.DatabaseConnection
 	var err error
 	switch cfg.UsedDB {
 	case "postgresql":
-		server.DBManager, err = db.NewPostgreSQLManager(fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s", cfg.PostgreSQL.Username, cfg.PostgreSQL.Password, cfg.PostgreSQL.Host, cfg.PostgreSQL.Port, cfg.PostgreSQL.DBName, cfg.PostgreSQL.SSLMode))
+		dbManager, err = db.NewPostgreSQLManager(context.Background(), postgreSQLUrl(cfg), time.Duration(cfg.Timeouts.QueryMs)*time.Millisecond)
+	case "sqlite":
+		dbManager, err = db.NewSQLiteManager(context.Background(), cfg.SQLite.Path, time.Duration(cfg.Timeouts.QueryMs)*time.Millisecond)
+	case "bolt":
+		dbManager, err = db.NewBoltManager(cfg.Bolt.Path)
+	default:
+		log.Fatalf("unknown used_db %q, expected \"postgresql\", \"sqlite\" or \"bolt\"", cfg.UsedDB)
 	}
 	if err != nil {
 		log.Fatalf("couldn't connect to db: %s", err)
 	}
+	return dbManager
+}
+
+func newAuthenticators(cfg config.MyConfig) []auth.Authenticator {
+	var authenticators []auth.Authenticator
+	if cfg.Auth.JWTSecret != "" {
+		authenticators = append(authenticators, auth.NewJWTAuthenticator(cfg.Auth.JWTSecret, cfg.Auth.JWTIssuer))
+	}
+	if len(cfg.Auth.APIKeys) > 0 {
+		keys := make(map[string]auth.Identity, len(cfg.Auth.APIKeys))
+		for key, apiKey := range cfg.Auth.APIKeys {
+			keys[key] = auth.Identity{UserID: apiKey.UserID, Roles: apiKey.Roles}
+		}
+		authenticators = append(authenticators, auth.NewAPIKeyAuthenticator(keys))
+	}
+	if len(authenticators) == 0 {
+		log.Warn("no authentication configured, only public routes are reachable")
+	}
+	return authenticators
+}
+
+// newTracer returns nil when tracing is disabled.
+func newTracer(cfg config.MyConfig) *tracing.Tracer {
+	var exporter tracing.Exporter
+	switch cfg.Tracing.Exporter {
+	case "":
+		return nil
+	case "otlp":
+		exporter = tracing.NewOTLPExporter(cfg.Tracing.Endpoint, 10*time.Second)
+	case "stdout":
+		exporter = tracing.NewWriterExporter(os.Stdout)
+	case "file":
+		fileExporter, err := tracing.NewFileExporter(cfg.Tracing.File)
+		if err != nil {
+			log.Fatalf("couldn't open traces file. err: [%s]", err)
+		}
+		exporter = fileExporter
+	default:
+		log.Fatalf("unknown tracing exporter %q", cfg.Tracing.Exporter)
+	}
+	serviceName := cfg.Tracing.ServiceName
+	if serviceName == "" {
+		serviceName = "adv-api"
+	}
+	return tracing.NewTracer(serviceName, exporter)
+}
+
+func newRouter(server routes.APIServer, cfg config.MyConfig, appMetrics *metrics.Metrics, tracer *tracing.Tracer) *mux.Router {
+	publicRoutes := make(map[string]bool)
+	for _, name := range cfg.Auth.PublicRoutes {
+		publicRoutes[name] = true
+	}
+	authenticators := newAuthenticators(cfg)
+	authMiddleware := auth.Middleware(authenticators)
+	optionalAuthMiddleware := auth.OptionalMiddleware(authenticators)
+	r := mux.NewRouter()
+	r.Methods(http.MethodGet).Path("/metrics").Name("metrics").Handler(appMetrics.Handler())
+	r.Methods(http.MethodGet).Path("/healthz").Name("healthz").HandlerFunc(server.Healthz)
+	var readyz http.Handler = http.HandlerFunc(server.Readyz)
+	if timeout := routeTimeout(cfg, "readyz"); timeout > 0 {
+		readyz = timeoutMiddleware(timeout)(readyz)
+	}
+	r.Methods(http.MethodGet).Path("/readyz").Name("readyz").Handler(readyz)
+	s := r.PathPrefix("/api/v1").Subrouter()
 	for _, route := range routes.GenerateRoutes(server) {
+		var handler http.Handler = route.HandlerFunc
+		if len(route.Roles) > 0 {
+			if publicRoutes[route.Name] {
+				log.Warnf("route %q requires roles and can't be public", route.Name)
+			}
+			handler = authMiddleware(auth.RequireRoles(route.Roles)(handler))
+		} else if !publicRoutes[route.Name] {
+			handler = authMiddleware(handler)
+		} else {
+			handler = optionalAuthMiddleware(handler)
+		}
+		if timeout := routeTimeout(cfg, route.Name); timeout > 0 {
+			handler = timeoutMiddleware(timeout)(handler)
+		}
+		handler = appMetrics.Middleware(route.Name)(handler)
+		handler = logging.Middleware(route.Name)(handler)
+		if tracer != nil {
+			handler = tracer.Middleware(route.Name)(handler)
+		}
 		s.Methods(route.Method).
 			Path(route.Pattern).
 			Name(route.Name).
-			Handler(Middleware(route.HandlerFunc))
+			Handler(Middleware(handler))
 	}
 	return r
 }
 
-func main() {
+func loadConfig() config.MyConfig {
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
 		log.Fatalf("empty path to config file")
@@ -49,10 +169,111 @@ func main() {
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
 		log.Fatalf("couldn't load config. error: [%s] path to config: [%s]", err, configPath)
-	} else {
-		router := newRouter(cfg)
-		address := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
-		log.Printf("Starting server on: %s", address)
-		log.Fatal(http.ListenAndServe(address, router))
+	}
+	if err := logging.Configure(cfg.Log.Level, cfg.Log.Format); err != nil {
+		log.Fatalf("couldn't configure logging. err: [%s]", err)
+	}
+	return cfg
+}
+
+const usage = `usage: app [command]
+
+commands:
+  serve                 run the API server (default)
+  migrate up            apply all pending migrations
+  migrate down [N|all]  revert N latest migrations (1 by default)
+  migrate status        show applied and pending migrations
+  version               print the build version`
+
+func main() {
+	command := "serve"
+	args := os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	switch command {
+	case "serve":
+		runServer(loadConfig())
+	case "migrate":
+		runMigrate(loadConfig(), args)
+	case "version":
+		fmt.Println(version)
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runServer(cfg config.MyConfig) {
+	if cfg.AutoMigrate {
+		autoMigrate(cfg)
+	}
+	appMetrics := metrics.New()
+	dbManager := newDBManager(cfg)
+	if pool, ok := dbManager.(metrics.PoolStatser); ok {
+		appMetrics.RegisterPoolStats(pool)
+	}
+	tracer := newTracer(cfg)
+	dbHooks := []db.Hook{logging.DBHook}
+	if tracer != nil {
+		dbHooks = append(dbHooks, tracer.DBHook(cfg.UsedDB))
+	}
+	dbHooks = append(dbHooks, appMetrics.DBHook)
+	server := routes.APIServer{
+		DBManager: db.Instrumented(dbManager, dbHooks...),
+		AdLimits: validation.AdLimits{
+			TitleMaxLength:       cfg.Validation.TitleMaxLength,
+			DescriptionMaxLength: cfg.Validation.DescriptionMaxLength,
+			PhotoLinksMax:        cfg.Validation.PhotoLinksMax,
+			PriceMax:             cfg.Validation.PriceMax,
+		},
+		MaxBodyBytes:   cfg.RequestBody.MaxBytes,
+		MaxBatchSize:   cfg.Batch.MaxAds,
+		IdempotencyTTL: time.Duration(cfg.Idempotency.TTLHours) * time.Hour,
+	}
+	ctx, stopPurgeJob := context.WithCancel(context.Background())
+	purgeJobDone := startPurgeJob(ctx, server.DBManager, cfg)
+	httpServer := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Handler: newRouter(server, cfg, appMetrics, tracer),
+	}
+	serve(httpServer, time.Duration(cfg.Shutdown.GracePeriodSeconds)*time.Second)
+	stopPurgeJob()
+	<-purgeJobDone
+	if err := server.DBManager.Close(); err != nil {
+		log.Errorf("couldn't close db connection. err: [%s]", err)
+	}
+	if tracer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := tracer.Shutdown(ctx); err != nil {
+			log.Errorf("couldn't export remaining spans. err: [%s]", err)
+		}
+		cancel()
+	}
+	log.Printf("Server stopped")
+}
+
+// serve blocks until SIGINT or SIGTERM and then lets in-flight requests finish within gracePeriod.
+func serve(httpServer *http.Server, gracePeriod time.Duration) {
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Starting server on: %s", httpServer.Addr)
+		serverErr <- httpServer.ListenAndServe()
+	}()
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	select {
+	case err := <-serverErr:
+		log.Fatalf("server failed: %s", err)
+	case sig := <-stop:
+		log.Printf("Received %s, shutting down", sig)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Errorf("couldn't drain in-flight requests in %s. err: [%s]", gracePeriod, err)
 	}
 }
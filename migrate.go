@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"adv-backend-trainee-assignment/config"
+	"adv-backend-trainee-assignment/migrations"
+	"adv-backend-trainee-assignment/src/migrate"
+	log "github.com/sirupsen/logrus"
+)
+
+func newMigrator(ctx context.Context, cfg config.MyConfig) (*migrate.Migrator, error) {
+	var loaded []migrate.Migration
+	var driver migrate.Driver
+	var err error
+	switch cfg.UsedDB {
+	case "postgresql":
+		if loaded, err = migrate.Load(migrations.FS); err == nil {
+			driver, err = migrate.NewPostgreSQLDriver(ctx, postgreSQLUrl(cfg))
+		}
+	case "sqlite":
+		if loaded, err = migrate.Load(migrations.SQLite); err == nil {
+			driver, err = migrate.NewSQLiteDriver(ctx, cfg.SQLite.Path)
+		}
+	case "bolt":
+		return nil, fmt.Errorf("bolt storage has no migrations, its buckets are created on start")
+	default:
+		return nil, fmt.Errorf("unknown used_db %q, expected \"postgresql\", \"sqlite\" or \"bolt\"", cfg.UsedDB)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(driver, loaded), nil
+}
+
+// autoMigrate applies pending migrations before serving. Instances starting together wait for each other on the lock.
+func autoMigrate(cfg config.MyConfig) {
+	if cfg.UsedDB == "bolt" {
+		return
+	}
+	ctx := context.Background()
+	migrator, err := newMigrator(ctx, cfg)
+	if err != nil {
+		log.Fatalf("couldn't prepare migrations. err: [%s]", err)
+	}
+	defer migrator.Close()
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		log.Fatalf("couldn't apply migrations. err: [%s]", err)
+	}
+	log.Printf("applied %d migrations", applied)
+}
+
+func runMigrate(cfg config.MyConfig, args []string) {
+	if len(args) == 0 || (args[0] != "up" && args[0] != "down" && args[0] != "status") {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	steps := 1
+	if args[0] == "down" && len(args) > 1 {
+		var err error
+		if args[1] == "all" {
+			steps = int(^uint(0) >> 1)
+		} else if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+			log.Fatalf("number of migrations to revert should be a positive integer or \"all\", got %q", args[1])
+		}
+	}
+	ctx := context.Background()
+	migrator, err := newMigrator(ctx, cfg)
+	if err != nil {
+		log.Fatalf("couldn't prepare migrations. err: [%s]", err)
+	}
+	defer migrator.Close()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("couldn't apply migrations. err: [%s]", err)
+		}
+		log.Printf("applied %d migrations", applied)
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Fatalf("couldn't revert migrations. err: [%s]", err)
+		}
+		log.Printf("reverted %d migrations", reverted)
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("couldn't get migrations status. err: [%s]", err)
+		}
+		printStatus(status)
+	}
+}
+
+func printStatus(status *migrate.Status) {
+	if status.Dirty {
+		fmt.Printf("version %d (dirty)\n\n", status.Version)
+	} else {
+		fmt.Printf("version %d\n\n", status.Version)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, migration := range status.Migrations {
+		state := "pending"
+		if migration.Applied {
+			state = "applied"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", migration.Version, migration.Name, state)
+	}
+	w.Flush()
+}
@@ -0,0 +1,2 @@
+alter table ads
+    drop column if exists updated_at;
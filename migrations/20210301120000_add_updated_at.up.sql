@@ -0,0 +1,6 @@
+alter table ads
+    add column if not exists updated_at integer;
+
+update ads
+set updated_at = created_at
+where updated_at is null;
@@ -0,0 +1,4 @@
+drop index if exists ads_deleted_at_idx;
+
+alter table ads
+    drop column if exists deleted_at;
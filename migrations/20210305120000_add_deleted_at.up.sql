@@ -0,0 +1,6 @@
+alter table ads
+    add column if not exists deleted_at integer;
+
+create index if not exists ads_deleted_at_idx
+    on ads (deleted_at)
+    where deleted_at is not null;
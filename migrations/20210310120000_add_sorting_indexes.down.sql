@@ -0,0 +1,3 @@
+drop index if exists ads_price_ad_id_idx;
+
+drop index if exists ads_created_at_ad_id_idx;
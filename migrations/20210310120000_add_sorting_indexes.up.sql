@@ -0,0 +1,7 @@
+create index if not exists ads_price_ad_id_idx
+    on ads (price, ad_id)
+    where deleted_at is null;
+
+create index if not exists ads_created_at_ad_id_idx
+    on ads (created_at, ad_id)
+    where deleted_at is null;
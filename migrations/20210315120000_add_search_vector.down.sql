@@ -0,0 +1,4 @@
+drop index if exists ads_search_vector_idx;
+
+alter table ads
+    drop column if exists search_vector;
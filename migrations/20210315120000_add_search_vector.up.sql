@@ -0,0 +1,9 @@
+alter table ads
+    add column if not exists search_vector tsvector
+        generated always as (
+            setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+            setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+        ) stored;
+
+create index if not exists ads_search_vector_idx
+    on ads using gin (search_vector);
@@ -0,0 +1,6 @@
+drop index if exists ads_category_id_idx;
+
+alter table ads
+    drop column if exists category_id;
+
+drop table if exists categories;
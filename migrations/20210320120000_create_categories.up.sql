@@ -0,0 +1,21 @@
+create table if not exists categories
+(
+    category_id text not null
+    constraint categories_pkey
+    primary key,
+    parent_id   text
+    constraint categories_parent_id_fkey
+    references categories (category_id),
+    name        text not null
+);
+
+create index if not exists categories_parent_id_idx
+    on categories (parent_id);
+
+alter table ads
+    add column if not exists category_id text
+        constraint ads_category_id_fkey
+            references categories (category_id);
+
+create index if not exists ads_category_id_idx
+    on ads (category_id);
@@ -0,0 +1,6 @@
+drop index if exists ads_owner_id_idx;
+
+alter table ads
+    drop column if exists owner_id;
+
+drop table if exists users;
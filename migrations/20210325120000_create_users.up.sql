@@ -0,0 +1,20 @@
+create table if not exists users
+(
+    user_id    text not null
+    constraint users_pkey
+    primary key,
+    name       text not null,
+    email      text not null,
+    created_at integer
+);
+
+create unique index if not exists users_email_idx
+    on users (lower(email));
+
+alter table ads
+    add column if not exists owner_id text
+        constraint ads_owner_id_fkey
+            references users (user_id);
+
+create index if not exists ads_owner_id_idx
+    on ads (owner_id);
@@ -0,0 +1,18 @@
+create table if not exists idempotency_keys
+(
+    owner_id      text    not null
+        constraint idempotency_keys_owner_id_fkey
+            references users (user_id)
+            on delete cascade,
+    key           text    not null,
+    request_hash  text    not null,
+    response_code integer,
+    response_body bytea,
+    created_at    integer not null,
+    expires_at    integer not null,
+    constraint idempotency_keys_pkey
+        primary key (owner_id, key)
+);
+
+create index if not exists idempotency_keys_expires_at_idx
+    on idempotency_keys (expires_at);
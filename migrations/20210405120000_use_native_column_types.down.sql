@@ -0,0 +1,41 @@
+-- the old integer columns can't hold prices above 2147483647 or times after 2038-01-19, so instead of
+-- failing with a bare "integer out of range" the migration names the problem and refuses to run until such rows are fixed
+do $$
+begin
+    if exists (select 1 from ads where price > 2147483647) then
+        raise exception 'can''t revert ads.price to integer: some ads have a price above 2147483647';
+    end if;
+    if exists (select 1 from ads where greatest(created_at, updated_at, deleted_at) >= to_timestamp(2147483647.5))
+        or exists (select 1 from users where created_at >= to_timestamp(2147483647.5))
+        or exists (select 1 from idempotency_keys where greatest(created_at, expires_at) >= to_timestamp(2147483647.5)) then
+        raise exception 'can''t revert timestamps to integer epochs: some are after 2038-01-19 03:14:07 UTC';
+    end if;
+end
+$$;
+
+alter table idempotency_keys
+    alter column created_at type integer using extract(epoch from created_at)::integer,
+    alter column expires_at type integer using extract(epoch from expires_at)::integer;
+
+alter table users
+    alter column created_at drop not null,
+    alter column created_at type integer using extract(epoch from created_at)::integer;
+
+alter table ads
+    drop constraint if exists ads_title_check,
+    drop constraint if exists ads_description_check,
+    drop constraint if exists ads_price_check,
+    drop constraint if exists ads_photo_links_check;
+
+alter table ads
+    alter column title drop not null,
+    alter column description drop not null,
+    alter column price drop not null,
+    alter column photo_links drop not null,
+    alter column created_at drop not null,
+    alter column updated_at drop not null,
+    alter column price type integer,
+    alter column created_at type integer using extract(epoch from created_at)::integer,
+    alter column updated_at type integer using extract(epoch from updated_at)::integer,
+    alter column deleted_at type integer using extract(epoch from deleted_at)::integer,
+    alter column photo_links type text using array_to_json(photo_links)::text;
@@ -0,0 +1,69 @@
+-- photo_links: a USING expression can't hold a subquery, so the array is built in a new column
+alter table ads
+    add column if not exists photo_links_array text[];
+
+update ads
+set photo_links_array = coalesce(array(select jsonb_array_elements_text(photo_links::jsonb)), '{}')
+where photo_links is not null;
+
+alter table ads
+    drop column photo_links;
+
+alter table ads
+    rename column photo_links_array to photo_links;
+
+update ads
+set photo_links = '{}'
+where photo_links is null;
+
+update ads
+set title = ''
+where title is null;
+
+update ads
+set description = ''
+where description is null;
+
+update ads
+set price = 0
+where price is null;
+
+update ads
+set created_at = coalesce(updated_at, extract(epoch from now())::integer)
+where created_at is null;
+
+update ads
+set updated_at = created_at
+where updated_at is null;
+
+alter table ads
+    alter column price type bigint,
+    alter column created_at type timestamptz using to_timestamp(created_at),
+    alter column updated_at type timestamptz using to_timestamp(updated_at),
+    alter column deleted_at type timestamptz using to_timestamp(deleted_at),
+    alter column title set not null,
+    alter column description set not null,
+    alter column price set not null,
+    alter column photo_links set not null,
+    alter column created_at set not null,
+    alter column updated_at set not null;
+
+-- rows written before validation existed may break these, so they're only enforced for new writes;
+-- run "alter table ads validate constraint ..." once such rows are fixed
+alter table ads
+    add constraint ads_title_check check (title <> '') not valid,
+    add constraint ads_description_check check (description <> '') not valid,
+    add constraint ads_price_check check (price >= 1) not valid,
+    add constraint ads_photo_links_check check (cardinality(photo_links) >= 1) not valid;
+
+update users
+set created_at = extract(epoch from now())::integer
+where created_at is null;
+
+alter table users
+    alter column created_at type timestamptz using to_timestamp(created_at),
+    alter column created_at set not null;
+
+alter table idempotency_keys
+    alter column created_at type timestamptz using to_timestamp(created_at),
+    alter column expires_at type timestamptz using to_timestamp(expires_at);
@@ -0,0 +1,18 @@
+// Package migrations embeds the SQL migrations so the binary can apply them without the migrate CLI.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// FS holds the PostgreSQL migrations.
+//
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFS embed.FS
+
+// SQLite holds the migrations of the sqlite backend, which has its own schema.
+var SQLite, _ = fs.Sub(sqliteFS, "sqlite")
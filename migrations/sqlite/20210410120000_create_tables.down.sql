@@ -0,0 +1,15 @@
+drop table if exists idempotency_keys;
+
+drop trigger if exists ads_search_update;
+
+drop trigger if exists ads_search_delete;
+
+drop trigger if exists ads_search_insert;
+
+drop table if exists ads_search;
+
+drop table if exists ads;
+
+drop table if exists users;
+
+drop table if exists categories;
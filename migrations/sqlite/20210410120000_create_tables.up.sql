@@ -0,0 +1,128 @@
+-- timestamps are unix nanoseconds, photo_links is a JSON array
+create table if not exists categories
+(
+    category_id text not null
+        constraint categories_pkey
+            primary key,
+    parent_id   text
+        constraint categories_parent_id_fkey
+            references categories (category_id),
+    name        text not null
+);
+
+create index if not exists categories_parent_id_idx
+    on categories (parent_id);
+
+create table if not exists users
+(
+    user_id    text    not null
+        constraint users_pkey
+            primary key,
+    name       text    not null,
+    email      text    not null,
+    -- lowercased in the application, because sqlite's lower() only folds ascii
+    email_key  text    not null,
+    created_at integer not null
+);
+
+create unique index if not exists users_email_idx
+    on users (email_key);
+
+-- the explicit integer key keeps rowids stable across vacuum, which the full text index relies on
+create table if not exists ads
+(
+    id          integer not null
+        constraint ads_pkey
+            primary key,
+    ad_id       text    not null
+        constraint ads_ad_id_key
+            unique,
+    title       text    not null
+        constraint ads_title_check
+            check (title <> ''),
+    description text    not null
+        constraint ads_description_check
+            check (description <> ''),
+    price       integer not null
+        constraint ads_price_check
+            check (price >= 1),
+    photo_links text    not null
+        constraint ads_photo_links_check
+            check (json_array_length(photo_links) >= 1),
+    category_id text
+        constraint ads_category_id_fkey
+            references categories (category_id),
+    owner_id    text
+        constraint ads_owner_id_fkey
+            references users (user_id),
+    created_at  integer not null,
+    updated_at  integer not null,
+    deleted_at  integer
+);
+
+create index if not exists ads_price_ad_id_idx
+    on ads (price, ad_id)
+    where deleted_at is null;
+
+create index if not exists ads_created_at_ad_id_idx
+    on ads (created_at, ad_id)
+    where deleted_at is null;
+
+create index if not exists ads_deleted_at_idx
+    on ads (deleted_at)
+    where deleted_at is not null;
+
+create index if not exists ads_category_id_idx
+    on ads (category_id);
+
+create index if not exists ads_owner_id_idx
+    on ads (owner_id);
+
+create virtual table if not exists ads_search using fts5
+(
+    title,
+    description,
+    content = 'ads',
+    content_rowid = 'id'
+);
+
+create trigger if not exists ads_search_insert
+    after insert
+    on ads
+begin
+    insert into ads_search (rowid, title, description) values (new.id, new.title, new.description);
+end;
+
+create trigger if not exists ads_search_delete
+    after delete
+    on ads
+begin
+    insert into ads_search (ads_search, rowid, title, description) values ('delete', old.id, old.title, old.description);
+end;
+
+create trigger if not exists ads_search_update
+    after update of title, description
+    on ads
+begin
+    insert into ads_search (ads_search, rowid, title, description) values ('delete', old.id, old.title, old.description);
+    insert into ads_search (rowid, title, description) values (new.id, new.title, new.description);
+end;
+
+create table if not exists idempotency_keys
+(
+    owner_id      text    not null
+        constraint idempotency_keys_owner_id_fkey
+            references users (user_id)
+            on delete cascade,
+    key           text    not null,
+    request_hash  text    not null,
+    response_code integer,
+    response_body blob,
+    created_at    integer not null,
+    expires_at    integer not null,
+    constraint idempotency_keys_pkey
+        primary key (owner_id, key)
+);
+
+create index if not exists idempotency_keys_expires_at_idx
+    on idempotency_keys (expires_at);
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"adv-backend-trainee-assignment/config"
+	"adv-backend-trainee-assignment/src/db"
+	log "github.com/sirupsen/logrus"
+)
+
+// startPurgeJob runs purging until ctx is canceled. The returned channel is closed when the job has stopped.
+func startPurgeJob(ctx context.Context, dbManager db.DatabaseConnection, cfg config.MyConfig) <-chan struct{} {
+	done := make(chan struct{})
+	retention := time.Duration(cfg.SoftDelete.RetentionHours) * time.Hour
+	if retention <= 0 {
+		log.Printf("soft deleted ads purging is disabled")
+	}
+	interval := time.Duration(cfg.SoftDelete.PurgeIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if retention > 0 {
+				purgeDeletedAds(ctx, dbManager, retention)
+			}
+			purgeIdempotencyKeys(ctx, dbManager)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return done
+}
+
+func purgeDeletedAds(ctx context.Context, dbManager db.DatabaseConnection, retention time.Duration) {
+	purged, err := dbManager.PurgeDeletedAds(ctx, time.Now().UTC().Add(-retention))
+	if err != nil {
+		log.Errorf("couldn't purge soft deleted ads. err: [%s]", err)
+	} else if purged > 0 {
+		log.Printf("purged %d soft deleted ads", purged)
+	}
+}
+
+func purgeIdempotencyKeys(ctx context.Context, dbManager db.DatabaseConnection) {
+	purged, err := dbManager.PurgeIdempotencyKeys(ctx, time.Now().UTC())
+	if err != nil {
+		log.Errorf("couldn't purge expired idempotency keys. err: [%s]", err)
+	} else if purged > 0 {
+		log.Printf("purged %d expired idempotency keys", purged)
+	}
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+type APIKeyAuthenticator struct {
+	keys map[string]Identity
+}
+
+func NewAPIKeyAuthenticator(keys map[string]Identity) *APIKeyAuthenticator {
+	normalized := make(map[string]Identity, len(keys))
+	for key, identity := range keys {
+		normalized[key] = NewIdentity(identity.UserID, identity.Roles)
+	}
+	return &APIKeyAuthenticator{keys: normalized}
+}
+
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+	var identity *Identity
+	for knownKey, knownIdentity := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(knownKey)) == 1 {
+			knownIdentity := knownIdentity
+			identity = &knownIdentity
+		}
+	}
+	if identity == nil {
+		return nil, ErrInvalidCredentials
+	}
+	return identity, nil
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyAuthenticator_Authenticate(t *testing.T) {
+	tests := []struct {
+		name             string
+		key              string
+		expectedIdentity *Identity
+		expectedErr      error
+	}{
+		{
+			name:             "Known key",
+			key:              "first-key",
+			expectedIdentity: &Identity{UserID: "first seller", Roles: []string{RoleSeller}},
+		},
+		{
+			name:             "Admin key",
+			key:              "admin-key",
+			expectedIdentity: &Identity{UserID: "admin", Roles: []string{RoleAdmin}},
+		},
+		{
+			name:        "Unknown key",
+			key:         "third-key",
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:        "No key",
+			expectedErr: ErrNoCredentials,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator := NewAPIKeyAuthenticator(map[string]Identity{
+				"first-key":  {UserID: "first seller"},
+				"second-key": {UserID: "second seller"},
+				"admin-key":  {UserID: "admin", Roles: []string{RoleAdmin}},
+			})
+			request, err := http.NewRequest(http.MethodGet, "/ads", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.key != "" {
+				request.Header.Set(APIKeyHeader, tt.key)
+			}
+			identity, err := authenticator.Authenticate(request)
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedIdentity, identity)
+		})
+	}
+}
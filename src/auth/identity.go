@@ -0,0 +1,44 @@
+package auth
+
+import "context"
+
+const (
+	RoleSeller    = "seller"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+type Identity struct {
+	UserID string
+	Roles  []string
+}
+
+// NewIdentity treats callers without explicit roles as sellers.
+func NewIdentity(userID string, roles []string) Identity {
+	if len(roles) == 0 {
+		roles = []string{RoleSeller}
+	}
+	return Identity{UserID: userID, Roles: roles}
+}
+
+func (identity Identity) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		for _, own := range identity.Roles {
+			if own == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(contextKey{}).(Identity)
+	return identity, ok
+}
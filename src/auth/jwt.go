@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+	Roles     []string `json:"roles"`
+}
+
+// JWTAuthenticator accepts HS256 signed tokens passed as "Authorization: Bearer <token>".
+// The "sub" claim is used as user id and the "roles" claim as list of roles.
+type JWTAuthenticator struct {
+	secret []byte
+	issuer string
+	now    func() time.Time
+}
+
+func NewJWTAuthenticator(secret string, issuer string) *JWTAuthenticator {
+	return &JWTAuthenticator{secret: []byte(secret), issuer: issuer, now: time.Now}
+}
+
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return nil, ErrNoCredentials
+	}
+	claims, err := a.parse(strings.TrimSpace(header[7:]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCredentials, err)
+	}
+	identity := NewIdentity(claims.Subject, claims.Roles)
+	return &identity, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.NewDecoder(bytes.NewReader(raw)).Decode(v)
+}
+
+func (a *JWTAuthenticator) sign(signingInput string) []byte {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
+
+func (a *JWTAuthenticator) parse(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed header")
+	}
+	if header.Alg != "HS256" {
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature")
+	}
+	if !hmac.Equal(signature, a.sign(parts[0]+"."+parts[1])) {
+		return nil, fmt.Errorf("signature mismatch")
+	}
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed claims")
+	}
+	now := a.now().Unix()
+	if claims.ExpiresAt != nil && now >= *claims.ExpiresAt {
+		return nil, fmt.Errorf("token expired")
+	}
+	if claims.NotBefore != nil && now < *claims.NotBefore {
+		return nil, fmt.Errorf("token not valid yet")
+	}
+	if a.issuer != "" && claims.Issuer != a.issuer {
+		return nil, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("empty subject")
+	}
+	return &claims, nil
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"adv-backend-trainee-assignment/src/problem"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrNoCredentials      = errors.New("no credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+type Authenticator interface {
+	// Authenticate returns ErrNoCredentials when the request carries no credentials of its kind,
+	// so the next authenticator can be tried.
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+func Middleware(authenticators []Authenticator) func(http.Handler) http.Handler {
+	return middleware(authenticators, false)
+}
+
+// OptionalMiddleware lets requests without credentials through anonymously, e.g. to public routes
+// that show more to the authenticated caller. Invalid credentials are still rejected.
+func OptionalMiddleware(authenticators []Authenticator) func(http.Handler) http.Handler {
+	return middleware(authenticators, true)
+}
+
+func middleware(authenticators []Authenticator, optional bool) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, authenticator := range authenticators {
+				identity, err := authenticator.Authenticate(r)
+				if err == ErrNoCredentials {
+					continue
+				} else if err != nil {
+					log.Debugf("rejected credentials for %s %s. err: [%s]", r.Method, r.URL.Path, err)
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "invalid credentials")
+					return
+				}
+				h.ServeHTTP(w, r.WithContext(NewContext(r.Context(), *identity)))
+				return
+			}
+			if optional {
+				h.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "missing credentials")
+		})
+	}
+}
+
+// RequireRoles must be applied after Middleware, so that the identity is already in the request context.
+func RequireRoles(roles []string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := FromContext(r.Context())
+			if !ok {
+				problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "missing credentials")
+				return
+			}
+			if !identity.HasRole(roles...) {
+				problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "not enough permissions")
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name               string
+		key                string
+		authorization      string
+		expectedOutputCode int
+		expectedUserID     string
+	}{
+		{
+			name:               "Authenticated with api key",
+			key:                "key",
+			expectedOutputCode: http.StatusOK,
+			expectedUserID:     "seller",
+		},
+		{
+			name:               "Authenticated with token",
+			authorization:      "Bearer " + signToken("secret", `{"alg":"HS256"}`, `{"sub":"another seller"}`),
+			expectedOutputCode: http.StatusOK,
+			expectedUserID:     "another seller",
+		},
+		{
+			name:               "Invalid token",
+			key:                "key",
+			authorization:      "Bearer token",
+			expectedOutputCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "No credentials",
+			expectedOutputCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var userID string
+			handler := Middleware([]Authenticator{
+				NewJWTAuthenticator("secret", ""),
+				NewAPIKeyAuthenticator(map[string]Identity{"key": {UserID: "seller"}}),
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				identity, _ := FromContext(r.Context())
+				userID = identity.UserID
+			}))
+			request, err := http.NewRequest(http.MethodGet, "/ads", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.key != "" {
+				request.Header.Set(APIKeyHeader, tt.key)
+			}
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, request)
+			assert.Equal(t, tt.expectedOutputCode, rr.Code)
+			assert.Equal(t, tt.expectedUserID, userID)
+			if tt.expectedOutputCode == http.StatusUnauthorized {
+				assert.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+				assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestOptionalMiddleware(t *testing.T) {
+	tests := []struct {
+		name               string
+		key                string
+		expectedOutputCode int
+		expectedIdentity   bool
+	}{
+		{
+			name:               "Authenticated",
+			key:                "key",
+			expectedOutputCode: http.StatusOK,
+			expectedIdentity:   true,
+		},
+		{
+			name:               "Anonymous",
+			expectedOutputCode: http.StatusOK,
+		},
+		{
+			name:               "Invalid key",
+			key:                "unknown",
+			expectedOutputCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var identified bool
+			handler := OptionalMiddleware([]Authenticator{
+				NewAPIKeyAuthenticator(map[string]Identity{"key": {UserID: "seller"}}),
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, identified = FromContext(r.Context())
+			}))
+			request, err := http.NewRequest(http.MethodGet, "/users/seller", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.key != "" {
+				request.Header.Set(APIKeyHeader, tt.key)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, request)
+			assert.Equal(t, tt.expectedOutputCode, rr.Code)
+			assert.Equal(t, tt.expectedIdentity, identified)
+		})
+	}
+}
+
+func TestRequireRoles(t *testing.T) {
+	tests := []struct {
+		name               string
+		identity           *Identity
+		roles              []string
+		expectedOutputCode int
+	}{
+		{
+			name:               "Role matches",
+			identity:           &Identity{UserID: "admin", Roles: []string{RoleAdmin}},
+			roles:              []string{RoleModerator, RoleAdmin},
+			expectedOutputCode: http.StatusOK,
+		},
+		{
+			name:               "Role doesn't match",
+			identity:           &Identity{UserID: "seller", Roles: []string{RoleSeller}},
+			roles:              []string{RoleModerator, RoleAdmin},
+			expectedOutputCode: http.StatusForbidden,
+		},
+		{
+			name:               "No identity",
+			roles:              []string{RoleAdmin},
+			expectedOutputCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireRoles(tt.roles)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			request, err := http.NewRequest(http.MethodPost, "/admin/ads/purge", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.identity != nil {
+				request = request.WithContext(NewContext(request.Context(), *tt.identity))
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, request)
+			assert.Equal(t, tt.expectedOutputCode, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,812 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltAds             = []byte("ads")
+	boltAdsByPrice      = []byte("ads_by_price")
+	boltAdsByCreatedAt  = []byte("ads_by_created_at")
+	boltAdsByDeletedAt  = []byte("ads_by_deleted_at")
+	boltAdsByToken      = []byte("ads_by_token")
+	boltCategories      = []byte("categories")
+	boltUsers           = []byte("users")
+	boltUsersByEmail    = []byte("users_by_email")
+	boltIdempotencyKeys = []byte("idempotency_keys")
+)
+
+// BoltManager keeps ads as JSON in a single file. Like the partial indexes in PostgreSQL, the price, created_at
+// and token indexes hold only ads that aren't deleted, so listing walks them in order instead of sorting,
+// counting seeks to the filtered range and search reads only ads containing the query words.
+type BoltManager struct {
+	db *bolt.DB
+}
+
+func NewBoltManager(path string) (*BoltManager, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("can't open bolt db: %s", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltAds, boltAdsByPrice, boltAdsByCreatedAt, boltAdsByDeletedAt, boltAdsByToken, boltCategories, boltUsers, boltUsersByEmail, boltIdempotencyKeys} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("can't open bolt db: %s", err)
+	}
+	return &BoltManager{db: db}, nil
+}
+
+// bolt doesn't take a context, so a deadline is checked before a transaction starts and between ads while walking them.
+func (manager BoltManager) view(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return manager.db.View(fn)
+}
+
+func (manager BoltManager) update(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return manager.db.Update(fn)
+}
+
+func (manager BoltManager) Ping(ctx context.Context) error {
+	return manager.view(ctx, func(tx *bolt.Tx) error {
+		return nil
+	})
+}
+
+func (manager BoltManager) Close() error {
+	return manager.db.Close()
+}
+
+// boltIndexKey orders keys by value and then by ad id. Flipping the sign bit keeps negative values first.
+func boltIndexKey(value int64, adID string) []byte {
+	key := make([]byte, 8, 8+len(adID))
+	binary.BigEndian.PutUint64(key, uint64(value)^(1<<63))
+	return append(key, adID...)
+}
+
+func boltGet(bucket *bolt.Bucket, key string, v interface{}) (bool, error) {
+	raw := bucket.Get([]byte(key))
+	if raw == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+func boltPut(bucket *bolt.Bucket, key string, v interface{}) error {
+	marshalled, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(key), marshalled)
+}
+
+func boltSelectAd(tx *bolt.Tx, adID string) (*models.DbAd, error) {
+	var ad models.DbAd
+	if ok, err := boltGet(tx.Bucket(boltAds), adID, &ad); !ok || err != nil {
+		return nil, err
+	}
+	return &ad, nil
+}
+
+// boltTokenKeys returns the token index keys of ad: every distinct word of its title and description
+// followed by a zero byte, which tokens can't contain, and the ad id.
+func boltTokenKeys(ad *models.DbAd) [][]byte {
+	var keys [][]byte
+	seen := map[string]bool{}
+	for _, token := range tokenize(ad.Title + " " + ad.Description) {
+		if !seen[token] {
+			seen[token] = true
+			keys = append(keys, []byte(token+"\x00"+ad.AdID))
+		}
+	}
+	return keys
+}
+
+// boltIndexAd adds or removes the index entries of ad depending on whether it's deleted.
+func boltIndexAd(tx *bolt.Tx, ad *models.DbAd, remove bool) error {
+	type entry struct {
+		bucket []byte
+		key    []byte
+	}
+	var entries []entry
+	if ad.DeletedAt == nil {
+		entries = append(entries, entry{boltAdsByPrice, boltIndexKey(ad.Price, ad.AdID)}, entry{boltAdsByCreatedAt, boltIndexKey(ad.CreatedAt.UnixNano(), ad.AdID)})
+		for _, key := range boltTokenKeys(ad) {
+			entries = append(entries, entry{boltAdsByToken, key})
+		}
+	} else {
+		entries = append(entries, entry{boltAdsByDeletedAt, boltIndexKey(ad.DeletedAt.UnixNano(), ad.AdID)})
+	}
+	for _, entry := range entries {
+		var err error
+		if remove {
+			err = tx.Bucket(entry.bucket).Delete(entry.key)
+		} else {
+			err = tx.Bucket(entry.bucket).Put(entry.key, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boltSaveAd replaces old with ad, keeping indexes in sync. old is nil for new ads.
+func boltSaveAd(tx *bolt.Tx, old *models.DbAd, ad *models.DbAd) error {
+	if old != nil {
+		if err := boltIndexAd(tx, old, true); err != nil {
+			return err
+		}
+	}
+	if err := boltIndexAd(tx, ad, false); err != nil {
+		return err
+	}
+	return boltPut(tx.Bucket(boltAds), ad.AdID, ad)
+}
+
+// boltCheckReferences stands in for foreign keys.
+func boltCheckReferences(tx *bolt.Tx, categoryID string, ownerID string) error {
+	if categoryID != "" && tx.Bucket(boltCategories).Get([]byte(categoryID)) == nil {
+		return fmt.Errorf("category %q doesn't exist", categoryID)
+	}
+	if ownerID != "" && tx.Bucket(boltUsers).Get([]byte(ownerID)) == nil {
+		return fmt.Errorf("user %q doesn't exist", ownerID)
+	}
+	return nil
+}
+
+func boltNewAd(tx *bolt.Tx, adData models.CreatingAd, ownerID string, now time.Time) (string, error) {
+	if err := boltCheckReferences(tx, adData.CategoryID, ownerID); err != nil {
+		return "", err
+	}
+	ad := models.DbAd{
+		AdID:        uuid.New().String(),
+		Title:       adData.Title,
+		Description: adData.Description,
+		Price:       adData.Price,
+		PhotoLinks:  adData.PhotoLinks,
+		CategoryID:  adData.CategoryID,
+		OwnerID:     ownerID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	return ad.AdID, boltSaveAd(tx, nil, &ad)
+}
+
+func (manager BoltManager) NewAd(ctx context.Context, adData models.CreatingAd, ownerID string) (string, error) {
+	var adID string
+	err := manager.update(ctx, func(tx *bolt.Tx) error {
+		var err error
+		adID, err = boltNewAd(tx, adData, ownerID, currentTime())
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return adID, nil
+}
+
+func (manager BoltManager) NewAds(ctx context.Context, ads []models.CreatingAd, ownerID string) ([]string, error) {
+	adIDs := make([]string, len(ads))
+	err := manager.update(ctx, func(tx *bolt.Tx) error {
+		now := currentTime()
+		for i, adData := range ads {
+			var err error
+			if adIDs[i], err = boltNewAd(tx, adData, ownerID, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return adIDs, nil
+}
+
+func (manager BoltManager) SelectAd(ctx context.Context, adID string) (*models.DbAd, error) {
+	var res *models.DbAd
+	err := manager.view(ctx, func(tx *bolt.Tx) error {
+		ad, err := boltSelectAd(tx, adID)
+		if ad != nil && ad.DeletedAt == nil {
+			res = ad
+		}
+		return err
+	})
+	return res, err
+}
+
+func boltAllCategories(tx *bolt.Tx) ([]*models.Category, error) {
+	result := []*models.Category{}
+	err := tx.Bucket(boltCategories).ForEach(func(k, v []byte) error {
+		var category models.Category
+		if err := json.Unmarshal(v, &category); err != nil {
+			return err
+		}
+		result = append(result, &category)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name == result[j].Name {
+			return result[i].CategoryID < result[j].CategoryID
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func boltCategoryTree(tx *bolt.Tx, rootID string) (map[string]bool, error) {
+	if rootID == "" {
+		return nil, nil
+	}
+	categories, err := boltAllCategories(tx)
+	if err != nil {
+		return nil, err
+	}
+	return categorySubtree(rootID, categories), nil
+}
+
+// boltWalkAds calls fn for ads that aren't deleted in the order of sortBy, starting after the cursor, until fn returns false.
+func boltWalkAds(ctx context.Context, tx *bolt.Tx, sortBy string, sortOrder string, after *models.AdsCursor, fn func(ad *models.DbAd) bool) error {
+	index := boltAdsByCreatedAt
+	if sortBy == "price" {
+		index = boltAdsByPrice
+	}
+	cursor := tx.Bucket(index).Cursor()
+	asc := sortOrder == "asc"
+	var key []byte
+	switch {
+	case after == nil && asc:
+		key, _ = cursor.First()
+	case after == nil:
+		key, _ = cursor.Last()
+	default:
+		value := after.CreatedAt.UnixNano()
+		if sortBy == "price" {
+			value = after.Price
+		}
+		start := boltIndexKey(value, after.AdID)
+		key, _ = cursor.Seek(start)
+		if asc {
+			if key != nil && bytes.Equal(key, start) {
+				key, _ = cursor.Next()
+			}
+		} else {
+			if key == nil {
+				key, _ = cursor.Last()
+			}
+			for key != nil && bytes.Compare(key, start) >= 0 {
+				key, _ = cursor.Prev()
+			}
+		}
+	}
+	for key != nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ad, err := boltSelectAd(tx, string(key[8:]))
+		if err != nil {
+			return err
+		} else if ad == nil {
+			return fmt.Errorf("index %s points to missing ad %q", index, key[8:])
+		}
+		if !fn(ad) {
+			return nil
+		}
+		if asc {
+			key, _ = cursor.Next()
+		} else {
+			key, _ = cursor.Prev()
+		}
+	}
+	return nil
+}
+
+func (manager BoltManager) GetAllAds(ctx context.Context, sortBy string, sortOrder string, page int, perPage int, filter models.AdsFilter, after *models.AdsCursor) ([]*models.DbAd, error) {
+	result := []*models.DbAd{}
+	err := manager.view(ctx, func(tx *bolt.Tx) error {
+		categoryTree, err := boltCategoryTree(tx, filter.CategoryID)
+		if err != nil {
+			return err
+		}
+		skip := (page - 1) * perPage
+		if after != nil {
+			skip = 0
+		}
+		return boltWalkAds(ctx, tx, sortBy, sortOrder, after, func(ad *models.DbAd) bool {
+			if !matchesAdsFilter(ad, filter, categoryTree) {
+				return true
+			}
+			if skip > 0 {
+				skip--
+				return true
+			}
+			result = append(result, ad)
+			return len(result) < perPage
+		})
+	})
+	return result, err
+}
+
+// boltTimeValue clamps t to the range of index values, which UnixNano doesn't cover.
+func boltTimeValue(t time.Time) int64 {
+	switch {
+	case t.Before(time.Unix(0, math.MinInt64)):
+		return math.MinInt64
+	case t.After(time.Unix(0, math.MaxInt64)):
+		return math.MaxInt64
+	}
+	return t.UnixNano()
+}
+
+// boltFilterRange picks the index bounding filter by price or created_at and the bounds of its values.
+// covered is true when the range is the whole filter, so the ads in it can be counted without reading them.
+func boltFilterRange(filter models.AdsFilter) (index []byte, from int64, to int64, covered bool) {
+	from, to = math.MinInt64, math.MaxInt64
+	rest := filter
+	if filter.MinPrice != nil || filter.MaxPrice != nil {
+		if filter.MinPrice != nil {
+			from = *filter.MinPrice
+		}
+		if filter.MaxPrice != nil {
+			to = *filter.MaxPrice
+		}
+		rest.MinPrice, rest.MaxPrice = nil, nil
+		return boltAdsByPrice, from, to, rest == models.AdsFilter{}
+	}
+	if filter.CreatedAfter != nil {
+		from = boltTimeValue(*filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		to = boltTimeValue(*filter.CreatedBefore)
+	}
+	rest.CreatedAfter, rest.CreatedBefore = nil, nil
+	return boltAdsByCreatedAt, from, to, rest == models.AdsFilter{}
+}
+
+func (manager BoltManager) CountAds(ctx context.Context, filter models.AdsFilter) (int64, error) {
+	var count int64
+	err := manager.view(ctx, func(tx *bolt.Tx) error {
+		if filter == (models.AdsFilter{}) {
+			count = int64(tx.Bucket(boltAdsByCreatedAt).Stats().KeyN)
+			return nil
+		}
+		categoryTree, err := boltCategoryTree(tx, filter.CategoryID)
+		if err != nil {
+			return err
+		}
+		index, from, to, covered := boltFilterRange(filter)
+		end := boltIndexKey(to, "")
+		cursor := tx.Bucket(index).Cursor()
+		for key, _ := cursor.Seek(boltIndexKey(from, "")); key != nil && bytes.Compare(key[:8], end) <= 0; key, _ = cursor.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if covered {
+				count++
+				continue
+			}
+			ad, err := boltSelectAd(tx, string(key[8:]))
+			if err != nil {
+				return err
+			} else if ad == nil {
+				return fmt.Errorf("index %s points to missing ad %q", index, key[8:])
+			}
+			if matchesAdsFilter(ad, filter, categoryTree) {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// boltSearchCandidates returns the ids of ads containing every query token.
+func boltSearchCandidates(ctx context.Context, tx *bolt.Tx, queryTokens []string) ([]string, error) {
+	var candidates map[string]bool
+	cursor := tx.Bucket(boltAdsByToken).Cursor()
+	for _, token := range queryTokens {
+		prefix := []byte(token + "\x00")
+		found := map[string]bool{}
+		for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			adID := string(key[len(prefix):])
+			if candidates == nil || candidates[adID] {
+				found[adID] = true
+			}
+		}
+		candidates = found
+		if len(candidates) == 0 {
+			break
+		}
+	}
+	adIDs := make([]string, 0, len(candidates))
+	for adID := range candidates {
+		adIDs = append(adIDs, adID)
+	}
+	return adIDs, nil
+}
+
+func (manager BoltManager) SearchAds(ctx context.Context, query string, page int, perPage int, withSnippets bool) ([]*models.FoundAd, error) {
+	queryTokens := tokenize(query)
+	var found []*models.FoundAd
+	err := manager.view(ctx, func(tx *bolt.Tx) error {
+		adIDs, err := boltSearchCandidates(ctx, tx, queryTokens)
+		if err != nil {
+			return err
+		}
+		for _, adID := range adIDs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			ad, err := boltSelectAd(tx, adID)
+			if err != nil {
+				return err
+			} else if ad == nil {
+				return fmt.Errorf("index %s points to missing ad %q", boltAdsByToken, adID)
+			}
+			if match := matchAd(ad, queryTokens, withSnippets); match != nil {
+				found = append(found, match)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortFoundAds(found)
+	offset := (page - 1) * perPage
+	if offset < 0 || offset >= len(found) {
+		return []*models.FoundAd{}, nil
+	}
+	limit := offset + perPage
+	if limit > len(found) {
+		limit = len(found)
+	}
+	return found[offset:limit], nil
+}
+
+// modifyAd saves the ad changed by modify. It returns nil when the ad doesn't exist or modify declines the change.
+func (manager BoltManager) modifyAd(ctx context.Context, adID string, modify func(tx *bolt.Tx, ad *models.DbAd) (bool, error)) (*models.DbAd, error) {
+	var res *models.DbAd
+	err := manager.update(ctx, func(tx *bolt.Tx) error {
+		old, err := boltSelectAd(tx, adID)
+		if err != nil || old == nil {
+			return err
+		}
+		ad := *old
+		if ok, err := modify(tx, &ad); !ok || err != nil {
+			return err
+		}
+		res = &ad
+		return boltSaveAd(tx, old, &ad)
+	})
+	return res, err
+}
+
+func (manager BoltManager) UpdateAd(ctx context.Context, adID string, adData models.UpdatingAd) (*models.DbAd, error) {
+	return manager.modifyAd(ctx, adID, func(tx *bolt.Tx, ad *models.DbAd) (bool, error) {
+		if ad.DeletedAt != nil {
+			return false, nil
+		}
+		if adData.Title != nil {
+			ad.Title = *adData.Title
+		}
+		if adData.Description != nil {
+			ad.Description = *adData.Description
+		}
+		if adData.Price != nil {
+			ad.Price = *adData.Price
+		}
+		if adData.PhotoLinks != nil {
+			ad.PhotoLinks = *adData.PhotoLinks
+		}
+		if adData.CategoryID != nil {
+			if err := boltCheckReferences(tx, *adData.CategoryID, ""); err != nil {
+				return false, err
+			}
+			ad.CategoryID = *adData.CategoryID
+		}
+		ad.UpdatedAt = currentTime()
+		return true, nil
+	})
+}
+
+func (manager BoltManager) DeleteAd(ctx context.Context, adID string) (bool, error) {
+	res, err := manager.modifyAd(ctx, adID, func(tx *bolt.Tx, ad *models.DbAd) (bool, error) {
+		if ad.DeletedAt != nil {
+			return false, nil
+		}
+		now := currentTime()
+		ad.DeletedAt = &now
+		return true, nil
+	})
+	return res != nil, err
+}
+
+func (manager BoltManager) RestoreAd(ctx context.Context, adID string) (bool, error) {
+	res, err := manager.modifyAd(ctx, adID, func(tx *bolt.Tx, ad *models.DbAd) (bool, error) {
+		if ad.DeletedAt == nil {
+			return false, nil
+		}
+		ad.DeletedAt = nil
+		ad.UpdatedAt = currentTime()
+		return true, nil
+	})
+	return res != nil, err
+}
+
+func (manager BoltManager) PurgeDeletedAds(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	err := manager.update(ctx, func(tx *bolt.Tx) error {
+		index := tx.Bucket(boltAdsByDeletedAt)
+		end := boltIndexKey(deletedBefore.UnixNano(), "")
+		var keys [][]byte
+		cursor := index.Cursor()
+		for key, _ := cursor.First(); key != nil && bytes.Compare(key, end) < 0; key, _ = cursor.Next() {
+			keys = append(keys, append([]byte(nil), key...))
+		}
+		for _, key := range keys {
+			if err := index.Delete(key); err != nil {
+				return err
+			}
+			if err := tx.Bucket(boltAds).Delete(key[8:]); err != nil {
+				return err
+			}
+			purged++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+func (manager BoltManager) NewCategory(ctx context.Context, category models.CreatingCategory) (string, error) {
+	categoryID := uuid.New().String()
+	err := manager.update(ctx, func(tx *bolt.Tx) error {
+		if category.ParentID != nil {
+			if err := boltCheckReferences(tx, *category.ParentID, ""); err != nil {
+				return err
+			}
+		}
+		return boltPut(tx.Bucket(boltCategories), categoryID, models.Category{CategoryID: categoryID, ParentID: category.ParentID, Name: category.Name})
+	})
+	if err != nil {
+		return "", err
+	}
+	return categoryID, nil
+}
+
+func (manager BoltManager) SelectCategory(ctx context.Context, categoryID string) (*models.Category, error) {
+	var res *models.Category
+	err := manager.view(ctx, func(tx *bolt.Tx) error {
+		var category models.Category
+		ok, err := boltGet(tx.Bucket(boltCategories), categoryID, &category)
+		if ok {
+			res = &category
+		}
+		return err
+	})
+	return res, err
+}
+
+func (manager BoltManager) GetAllCategories(ctx context.Context) ([]*models.Category, error) {
+	var res []*models.Category
+	err := manager.view(ctx, func(tx *bolt.Tx) error {
+		var err error
+		res, err = boltAllCategories(tx)
+		return err
+	})
+	return res, err
+}
+
+func (manager BoltManager) UpdateCategory(ctx context.Context, categoryID string, category models.CreatingCategory) (*models.Category, error) {
+	var res *models.Category
+	err := manager.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltCategories)
+		if bucket.Get([]byte(categoryID)) == nil {
+			return nil
+		}
+		if category.ParentID != nil {
+			if err := boltCheckReferences(tx, *category.ParentID, ""); err != nil {
+				return err
+			}
+		}
+		res = &models.Category{CategoryID: categoryID, ParentID: category.ParentID, Name: category.Name}
+		return boltPut(bucket, categoryID, res)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (manager BoltManager) DeleteCategory(ctx context.Context, categoryID string) (bool, error) {
+	var deleted bool
+	err := manager.update(ctx, func(tx *bolt.Tx) error {
+		categories, err := boltAllCategories(tx)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, category := range categories {
+			if category.ParentID != nil && *category.ParentID == categoryID {
+				return ErrCategoryNotEmpty
+			}
+			found = found || category.CategoryID == categoryID
+		}
+		if !found {
+			return nil
+		}
+		// deleted ads still reference the category until they're purged
+		err = tx.Bucket(boltAds).ForEach(func(k, v []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var ad models.DbAd
+			if err := json.Unmarshal(v, &ad); err != nil {
+				return err
+			}
+			if ad.CategoryID == categoryID {
+				return ErrCategoryNotEmpty
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		deleted = true
+		return tx.Bucket(boltCategories).Delete([]byte(categoryID))
+	})
+	return deleted, err
+}
+
+func (manager BoltManager) NewUser(ctx context.Context, user models.CreatingUser) (string, error) {
+	userID := uuid.New().String()
+	err := manager.update(ctx, func(tx *bolt.Tx) error {
+		byEmail := tx.Bucket(boltUsersByEmail)
+		emailKey := []byte(strings.ToLower(user.Email))
+		if byEmail.Get(emailKey) != nil {
+			return ErrEmailTaken
+		}
+		if err := byEmail.Put(emailKey, []byte(userID)); err != nil {
+			return err
+		}
+		return boltPut(tx.Bucket(boltUsers), userID, models.User{UserID: userID, Name: user.Name, Email: user.Email, CreatedAt: currentTime()})
+	})
+	if err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+func (manager BoltManager) SelectUser(ctx context.Context, userID string) (*models.User, error) {
+	var res *models.User
+	err := manager.view(ctx, func(tx *bolt.Tx) error {
+		var user models.User
+		ok, err := boltGet(tx.Bucket(boltUsers), userID, &user)
+		if ok {
+			res = &user
+		}
+		return err
+	})
+	return res, err
+}
+
+// idempotency keys are stored by owner id and key joined with a zero byte, which can't appear in a header.
+func boltIdempotencyKey(ownerID string, key string) string {
+	return ownerID + "\x00" + key
+}
+
+func (manager BoltManager) ReserveIdempotencyKey(ctx context.Context, ownerID string, key string, requestHash string, expiresAt time.Time) (*models.IdempotencyRecord, error) {
+	var res *models.IdempotencyRecord
+	err := manager.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltIdempotencyKeys)
+		id := boltIdempotencyKey(ownerID, key)
+		var existing models.IdempotencyRecord
+		ok, err := boltGet(bucket, id, &existing)
+		if err != nil {
+			return err
+		}
+		if ok && existing.ExpiresAt.After(time.Now()) {
+			res = &existing
+			return nil
+		}
+		if err := boltCheckReferences(tx, "", ownerID); err != nil {
+			return err
+		}
+		return boltPut(bucket, id, models.IdempotencyRecord{OwnerID: ownerID, Key: key, RequestHash: requestHash, ExpiresAt: expiresAt.UTC().Truncate(time.Microsecond)})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (manager BoltManager) CompleteIdempotencyKey(ctx context.Context, ownerID string, key string, responseCode int, responseBody []byte) error {
+	return manager.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltIdempotencyKeys)
+		id := boltIdempotencyKey(ownerID, key)
+		var record models.IdempotencyRecord
+		if ok, err := boltGet(bucket, id, &record); !ok || err != nil {
+			return err
+		}
+		record.ResponseCode = responseCode
+		record.ResponseBody = responseBody
+		return boltPut(bucket, id, record)
+	})
+}
+
+func (manager BoltManager) ReleaseIdempotencyKey(ctx context.Context, ownerID string, key string) error {
+	return manager.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltIdempotencyKeys)
+		id := boltIdempotencyKey(ownerID, key)
+		var record models.IdempotencyRecord
+		if ok, err := boltGet(bucket, id, &record); !ok || err != nil || record.ResponseCode != 0 {
+			return err
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+func (manager BoltManager) PurgeIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	var purged int64
+	err := manager.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltIdempotencyKeys)
+		var expired [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			var record models.IdempotencyRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			if record.ExpiresAt.Before(expiredBefore) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+			purged++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
@@ -0,0 +1,306 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBoltManager(t *testing.T, path string) *BoltManager {
+	manager, err := NewBoltManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		manager.Close()
+	})
+	return manager
+}
+
+func TestBoltManager_GetAllAds(t *testing.T) {
+	manager := newTestBoltManager(t, filepath.Join(t.TempDir(), "ads.db"))
+	ctx := context.Background()
+	ownerID, err := manager.NewUser(ctx, models.CreatingUser{Name: "owner", Email: "owner@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	categoryID, err := manager.NewCategory(ctx, models.CreatingCategory{Name: "category"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, price := range []int64{300, 100, 200, 100, 400} {
+		_, err := manager.NewAd(ctx, models.CreatingAd{Title: "Диван", Description: "Синий", Price: price, PhotoLinks: []string{"https://ya.ru"}, CategoryID: categoryID}, ownerID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	prices := func(ads []*models.DbAd) []int64 {
+		var result []int64
+		for _, ad := range ads {
+			result = append(result, ad.Price)
+		}
+		return result
+	}
+
+	ads, err := manager.GetAllAds(ctx, "price", "asc", 1, 3, models.AdsFilter{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{100, 100, 200}, prices(ads))
+	assert.True(t, ads[0].AdID < ads[1].AdID)
+	next, err := manager.GetAllAds(ctx, "price", "asc", 1, 3, models.AdsFilter{}, &models.AdsCursor{Price: ads[1].Price, AdID: ads[1].AdID})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{200, 300, 400}, prices(next))
+	ads, err = manager.GetAllAds(ctx, "price", "desc", 2, 2, models.AdsFilter{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{200, 100}, prices(ads))
+	next, err = manager.GetAllAds(ctx, "price", "desc", 1, 10, models.AdsFilter{}, &models.AdsCursor{Price: ads[1].Price, AdID: ads[1].AdID})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{100}, prices(next))
+
+	ads, err = manager.GetAllAds(ctx, "created_at", "desc", 1, 10, models.AdsFilter{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{400, 100, 200, 100, 300}, prices(ads))
+	next, err = manager.GetAllAds(ctx, "created_at", "asc", 1, 2, models.AdsFilter{}, &models.AdsCursor{CreatedAt: ads[2].CreatedAt, AdID: ads[2].AdID})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{100, 400}, prices(next))
+
+	minPrice := int64(150)
+	ads, err = manager.GetAllAds(ctx, "price", "asc", 1, 10, models.AdsFilter{MinPrice: &minPrice, Query: "СИН", CategoryID: categoryID}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{200, 300, 400}, prices(ads))
+	count, err := manager.CountAds(ctx, models.AdsFilter{MinPrice: &minPrice})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	count, err = manager.CountAds(ctx, models.AdsFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+	maxPrice := int64(300)
+	count, err = manager.CountAds(ctx, models.AdsFilter{MinPrice: &minPrice, MaxPrice: &maxPrice, OwnerID: ownerID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	createdAfter, createdBefore := ads[0].CreatedAt, time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)
+	count, err = manager.CountAds(ctx, models.AdsFilter{CreatedAfter: &createdAfter, CreatedBefore: &createdBefore})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+// expiringContext lets the first checks calls to Err pass and then reports an exceeded deadline.
+type expiringContext struct {
+	context.Context
+	checks int
+}
+
+func (ctx *expiringContext) Err() error {
+	if ctx.checks == 0 {
+		return context.DeadlineExceeded
+	}
+	ctx.checks--
+	return nil
+}
+
+func TestBoltManager_WalkChecksContext(t *testing.T) {
+	manager := newTestBoltManager(t, filepath.Join(t.TempDir(), "ads.db"))
+	ctx := context.Background()
+	ownerID, err := manager.NewUser(ctx, models.CreatingUser{Name: "owner", Email: "owner@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, price := range []int64{100, 200, 300} {
+		if _, err := manager.NewAd(ctx, models.CreatingAd{Title: "title", Description: "description", Price: price, PhotoLinks: []string{"https://ya.ru"}}, ownerID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	minPrice := int64(0)
+	_, err = manager.CountAds(&expiringContext{Context: ctx, checks: 2}, models.AdsFilter{MinPrice: &minPrice, OwnerID: ownerID})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	_, err = manager.SearchAds(&expiringContext{Context: ctx, checks: 2}, "title", 1, 10, false)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	_, err = manager.GetAllAds(&expiringContext{Context: ctx, checks: 2}, "price", "asc", 1, 10, models.AdsFilter{}, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestBoltManager_Ads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ads.db")
+	manager := newTestBoltManager(t, path)
+	ctx := context.Background()
+	ownerID, err := manager.NewUser(ctx, models.CreatingUser{Name: "owner", Email: "owner@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	adIDs, err := manager.NewAds(ctx, []models.CreatingAd{
+		{Title: "Красный велосипед", Description: "Почти новый", Price: 100, PhotoLinks: []string{"https://ya.ru"}},
+		{Title: "Синий диван", Description: "Подойдёт к красному велосипеду", Price: 200, PhotoLinks: []string{"https://ya.ru"}},
+	}, ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = manager.NewAds(ctx, []models.CreatingAd{
+		{Title: "title", Description: "description", Price: 1, PhotoLinks: []string{"https://ya.ru"}},
+		{Title: "title", Description: "description", Price: 1, PhotoLinks: []string{"https://ya.ru"}, CategoryID: "missing"},
+	}, ownerID)
+	assert.Error(t, err)
+	_, err = manager.NewAd(ctx, models.CreatingAd{Title: "title", Description: "description", Price: 1, PhotoLinks: []string{"https://ya.ru"}}, "missing")
+	assert.Error(t, err)
+
+	price := int64(50)
+	updated, err := manager.UpdateAd(ctx, adIDs[1], models.UpdatingAd{Price: &price})
+	assert.NoError(t, err)
+	assert.Equal(t, price, updated.Price)
+	assert.Equal(t, "Синий диван", updated.Title)
+	ads, err := manager.GetAllAds(ctx, "price", "asc", 1, 10, models.AdsFilter{}, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, ads, 2) {
+		assert.Equal(t, adIDs[1], ads[0].AdID)
+	}
+
+	found, err := manager.SearchAds(ctx, "красный велосипед", 1, 10, true)
+	assert.NoError(t, err)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, adIDs[0], found[0].AdID)
+		assert.Contains(t, found[0].Snippet, "<b>Красный</b>")
+	}
+
+	deleted, err := manager.DeleteAd(ctx, adIDs[0])
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = manager.DeleteAd(ctx, adIDs[0])
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+	selected, err := manager.SelectAd(ctx, adIDs[0])
+	assert.NoError(t, err)
+	assert.Nil(t, selected)
+	count, err := manager.CountAds(ctx, models.AdsFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	restored, err := manager.RestoreAd(ctx, adIDs[0])
+	assert.NoError(t, err)
+	assert.True(t, restored)
+	restored, err = manager.RestoreAd(ctx, adIDs[0])
+	assert.NoError(t, err)
+	assert.False(t, restored)
+	_, err = manager.DeleteAd(ctx, adIDs[1])
+	assert.NoError(t, err)
+
+	assert.NoError(t, manager.Close())
+	manager = newTestBoltManager(t, path)
+	selected, err = manager.SelectAd(ctx, adIDs[0])
+	assert.NoError(t, err)
+	if assert.NotNil(t, selected) {
+		assert.Equal(t, []string{"https://ya.ru"}, selected.PhotoLinks)
+		assert.Equal(t, ownerID, selected.OwnerID)
+	}
+	purged, err := manager.PurgeDeletedAds(ctx, time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), purged)
+	purged, err = manager.PurgeDeletedAds(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	restored, err = manager.RestoreAd(ctx, adIDs[1])
+	assert.NoError(t, err)
+	assert.False(t, restored)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = manager.SelectAd(canceled, adIDs[0])
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestBoltManager_Categories(t *testing.T) {
+	manager := newTestBoltManager(t, filepath.Join(t.TempDir(), "ads.db"))
+	ctx := context.Background()
+	ownerID, err := manager.NewUser(ctx, models.CreatingUser{Name: "owner", Email: "owner@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parentID, err := manager.NewCategory(ctx, models.CreatingCategory{Name: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	childID, err := manager.NewCategory(ctx, models.CreatingCategory{Name: "a", ParentID: &parentID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := "missing"
+	_, err = manager.NewCategory(ctx, models.CreatingCategory{Name: "c", ParentID: &missing})
+	assert.Error(t, err)
+	categories, err := manager.GetAllCategories(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Category{{CategoryID: childID, ParentID: &parentID, Name: "a"}, {CategoryID: parentID, Name: "b"}}, categories)
+	updated, err := manager.UpdateCategory(ctx, missing, models.CreatingCategory{Name: "c"})
+	assert.NoError(t, err)
+	assert.Nil(t, updated)
+
+	_, err = manager.DeleteCategory(ctx, parentID)
+	assert.Equal(t, ErrCategoryNotEmpty, err)
+	adID, err := manager.NewAd(ctx, models.CreatingAd{Title: "title", Description: "description", Price: 1, PhotoLinks: []string{"https://ya.ru"}, CategoryID: childID}, ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = manager.DeleteAd(ctx, adID)
+	assert.NoError(t, err)
+	_, err = manager.DeleteCategory(ctx, childID)
+	assert.Equal(t, ErrCategoryNotEmpty, err)
+	_, err = manager.PurgeDeletedAds(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	deleted, err := manager.DeleteCategory(ctx, childID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = manager.DeleteCategory(ctx, childID)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+func TestBoltManager_Users(t *testing.T) {
+	manager := newTestBoltManager(t, filepath.Join(t.TempDir(), "ads.db"))
+	ctx := context.Background()
+	userID, err := manager.NewUser(ctx, models.CreatingUser{Name: "user", Email: "Пользователь@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := manager.SelectUser(ctx, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Пользователь@example.com", user.Email)
+	_, err = manager.NewUser(ctx, models.CreatingUser{Name: "other", Email: "пользователь@EXAMPLE.com"})
+	assert.Equal(t, ErrEmailTaken, err)
+	missing, err := manager.SelectUser(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestBoltManager_IdempotencyKeys(t *testing.T) {
+	manager := newTestBoltManager(t, filepath.Join(t.TempDir(), "ads.db"))
+	ctx := context.Background()
+	ownerID, err := manager.NewUser(ctx, models.CreatingUser{Name: "owner", Email: "owner@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiresAt := time.Now().Add(time.Hour)
+
+	record, err := manager.ReserveIdempotencyKey(ctx, ownerID, "key", "hash", expiresAt)
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+	assert.NoError(t, manager.CompleteIdempotencyKey(ctx, ownerID, "key", 201, []byte(`{"adID":"id"}`)))
+	assert.NoError(t, manager.ReleaseIdempotencyKey(ctx, ownerID, "key"))
+	record, err = manager.ReserveIdempotencyKey(ctx, ownerID, "key", "other", expiresAt)
+	assert.NoError(t, err)
+	if assert.NotNil(t, record) {
+		assert.Equal(t, "hash", record.RequestHash)
+		assert.Equal(t, 201, record.ResponseCode)
+		assert.Equal(t, []byte(`{"adID":"id"}`), record.ResponseBody)
+	}
+
+	record, err = manager.ReserveIdempotencyKey(ctx, ownerID, "pending", "hash", expiresAt)
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+	assert.NoError(t, manager.ReleaseIdempotencyKey(ctx, ownerID, "pending"))
+	record, err = manager.ReserveIdempotencyKey(ctx, ownerID, "pending", "other", expiresAt)
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+
+	purged, err := manager.PurgeIdempotencyKeys(ctx, expiresAt.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+}
@@ -0,0 +1,101 @@
+package db_test
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"adv-backend-trainee-assignment/migrations"
+	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/db/dbtest"
+	"adv-backend-trainee-assignment/src/migrate"
+	"github.com/jackc/pgx/v4"
+)
+
+// postgreSQLDsnEnv points the conformance suite at a PostgreSQL database. All of its data is deleted.
+const postgreSQLDsnEnv = "ADV_TEST_POSTGRES_DSN"
+
+func migrateUp(t *testing.T, driver migrate.Driver, fsys fs.FS) {
+	loaded, err := migrate.Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator := migrate.New(driver, loaded)
+	defer migrator.Close()
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMockedDBManager_Conformance(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) db.DatabaseConnection {
+		return db.NewMockedDBManager()
+	})
+}
+
+func TestSQLiteManager_Conformance(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) db.DatabaseConnection {
+		ctx := context.Background()
+		path := filepath.Join(t.TempDir(), "ads.db")
+		driver, err := migrate.NewSQLiteDriver(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		migrateUp(t, driver, migrations.SQLite)
+		manager, err := db.NewSQLiteManager(ctx, path, 5*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			manager.Close()
+		})
+		return manager
+	})
+}
+
+func TestBoltManager_Conformance(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) db.DatabaseConnection {
+		manager, err := db.NewBoltManager(filepath.Join(t.TempDir(), "ads.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			manager.Close()
+		})
+		return manager
+	})
+}
+
+func TestPostgreSQLManager_Conformance(t *testing.T) {
+	dsn := os.Getenv(postgreSQLDsnEnv)
+	if dsn == "" {
+		t.Skipf("%s isn't set", postgreSQLDsnEnv)
+	}
+	ctx := context.Background()
+	driver, err := migrate.NewPostgreSQLDriver(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrateUp(t, driver, migrations.FS)
+	dbtest.Run(t, func(t *testing.T) db.DatabaseConnection {
+		conn, err := pgx.Connect(ctx, dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close(ctx)
+		if _, err := conn.Exec(ctx, "TRUNCATE ads, categories, users, idempotency_keys"); err != nil {
+			t.Fatal(err)
+		}
+		manager, err := db.NewPostgreSQLManager(ctx, dsn, 5*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			manager.Close()
+		})
+		return manager
+	})
+}
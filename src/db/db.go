@@ -1,10 +1,62 @@
 package db
 
-import "adv-backend-trainee-assignment/src/models"
+import (
+	"context"
+	"errors"
+	"time"
+
+	"adv-backend-trainee-assignment/src/models"
+)
+
+var (
+	ErrCategoryNotEmpty = errors.New("category has subcategories or ads")
+	ErrEmailTaken       = errors.New("email is already taken")
+)
+
+// currentTime is truncated to microseconds, the precision of PostgreSQL timestamps, so every backend stores the same value.
+func currentTime() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// nullableString stores an empty id as NULL, so it doesn't violate foreign keys.
+func nullableString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+type PoolStats struct {
+	Acquired int32
+	Idle     int32
+	Total    int32
+}
 
 type DatabaseConnection interface {
-	NewAd(adData models.CreatingAd) (string, error)
-	SelectAd(adID string) (*models.DbAd, error)
-	GetAllAds(sortBy string, sortOrder string, page int, perPage int) ([]*models.DbAd, error)
+	NewAd(ctx context.Context, adData models.CreatingAd, ownerID string) (string, error)
+	// NewAds inserts all ads or none of them and returns their ids in the same order.
+	NewAds(ctx context.Context, ads []models.CreatingAd, ownerID string) ([]string, error)
+	SelectAd(ctx context.Context, adID string) (*models.DbAd, error)
+	GetAllAds(ctx context.Context, sortBy string, sortOrder string, page int, perPage int, filter models.AdsFilter, after *models.AdsCursor) ([]*models.DbAd, error)
+	CountAds(ctx context.Context, filter models.AdsFilter) (int64, error)
+	SearchAds(ctx context.Context, query string, page int, perPage int, withSnippets bool) ([]*models.FoundAd, error)
+	UpdateAd(ctx context.Context, adID string, adData models.UpdatingAd) (*models.DbAd, error)
+	DeleteAd(ctx context.Context, adID string) (bool, error)
+	RestoreAd(ctx context.Context, adID string) (bool, error)
+	PurgeDeletedAds(ctx context.Context, deletedBefore time.Time) (int64, error)
+	NewCategory(ctx context.Context, category models.CreatingCategory) (string, error)
+	SelectCategory(ctx context.Context, categoryID string) (*models.Category, error)
+	GetAllCategories(ctx context.Context) ([]*models.Category, error)
+	UpdateCategory(ctx context.Context, categoryID string, category models.CreatingCategory) (*models.Category, error)
+	DeleteCategory(ctx context.Context, categoryID string) (bool, error)
+	NewUser(ctx context.Context, user models.CreatingUser) (string, error)
+	SelectUser(ctx context.Context, userID string) (*models.User, error)
+	// ReserveIdempotencyKey stores a pending record and returns nil, or returns the unexpired record already stored for the key.
+	ReserveIdempotencyKey(ctx context.Context, ownerID string, key string, requestHash string, expiresAt time.Time) (*models.IdempotencyRecord, error)
+	CompleteIdempotencyKey(ctx context.Context, ownerID string, key string, responseCode int, responseBody []byte) error
+	// ReleaseIdempotencyKey removes a pending record, so the request can be retried with the same key.
+	ReleaseIdempotencyKey(ctx context.Context, ownerID string, key string) error
+	PurgeIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (int64, error)
+	Ping(ctx context.Context) error
 	Close() error
 }
@@ -0,0 +1,687 @@
+// Package dbtest is a conformance suite for db.DatabaseConnection implementations.
+// Every backend, the mock included, has to pass it, so tests written against the mock hold in production.
+package dbtest
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// Run runs the suite. newDB must return an empty database, it's called once per subtest.
+func Run(t *testing.T, newDB func(t *testing.T) db.DatabaseConnection) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, conn db.DatabaseConnection)
+	}{
+		{"Ads", testAds},
+		{"Batch ads", testNewAds},
+		{"Sorting ties", testSortingTies},
+		{"Pagination", testPagination},
+		{"Cursor pagination", testCursorPagination},
+		{"Filters", testFilters},
+		{"Search", testSearch},
+		{"Soft delete", testSoftDelete},
+		{"Categories", testCategories},
+		{"Users", testUsers},
+		{"Idempotency keys", testIdempotencyKeys},
+		{"Concurrent ads", testConcurrentAds},
+		{"Concurrent users", testConcurrentUsers},
+		{"Concurrent idempotency keys", testConcurrentIdempotencyKeys},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newDB(t))
+		})
+	}
+}
+
+func newUser(t *testing.T, conn db.DatabaseConnection, email string) string {
+	userID, err := conn.NewUser(context.Background(), models.CreatingUser{Name: "user", Email: email})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userID
+}
+
+func newCategory(t *testing.T, conn db.DatabaseConnection, name string, parentID *string) string {
+	categoryID, err := conn.NewCategory(context.Background(), models.CreatingCategory{Name: name, ParentID: parentID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return categoryID
+}
+
+func creatingAd(title string, price int64) models.CreatingAd {
+	return models.CreatingAd{Title: title, Description: "description of " + title, Price: price, PhotoLinks: []string{"https://example.com/1.jpg", "https://example.com/2.jpg"}}
+}
+
+func newAd(t *testing.T, conn db.DatabaseConnection, adData models.CreatingAd, ownerID string) string {
+	adID, err := conn.NewAd(context.Background(), adData, ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return adID
+}
+
+func getAllAds(t *testing.T, conn db.DatabaseConnection, sortBy string, sortOrder string, page int, perPage int, filter models.AdsFilter, after *models.AdsCursor) []*models.DbAd {
+	ads, err := conn.GetAllAds(context.Background(), sortBy, sortOrder, page, perPage, filter, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ads
+}
+
+func adIDs(ads []*models.DbAd) []string {
+	ids := make([]string, len(ads))
+	for i, ad := range ads {
+		ids[i] = ad.AdID
+	}
+	return ids
+}
+
+func cursorOf(ad *models.DbAd, sortBy string, sortOrder string) *models.AdsCursor {
+	return &models.AdsCursor{SortBy: sortBy, SortOrder: sortOrder, Price: ad.Price, CreatedAt: ad.CreatedAt, AdID: ad.AdID}
+}
+
+// assertStoredTime checks that value is in UTC with microsecond precision, as PostgreSQL stores it, and lies within [from, time.Now()].
+func assertStoredTime(t *testing.T, from time.Time, value time.Time) {
+	t.Helper()
+	assert.Equal(t, time.UTC, value.Location())
+	assert.True(t, value.Equal(value.Truncate(time.Microsecond)), "%s has sub-microsecond precision", value)
+	assert.False(t, value.Before(from.Truncate(time.Microsecond)), "%s is before %s", value, from)
+	assert.False(t, value.After(time.Now()), "%s is in the future", value)
+}
+
+func testAds(t *testing.T, conn db.DatabaseConnection) {
+	ctx := context.Background()
+	ownerID := newUser(t, conn, "owner@example.com")
+	categoryID := newCategory(t, conn, "Transport", nil)
+	start := time.Now()
+	adData := creatingAd("Red bicycle", 100)
+	adData.CategoryID = categoryID
+	adID := newAd(t, conn, adData, ownerID)
+	uncategorizedID := newAd(t, conn, creatingAd("Blue sofa", 200), ownerID)
+
+	ad, err := conn.SelectAd(ctx, adID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, ad) {
+		assert.Equal(t, adID, ad.AdID)
+		assert.Equal(t, adData.Title, ad.Title)
+		assert.Equal(t, adData.Description, ad.Description)
+		assert.Equal(t, adData.Price, ad.Price)
+		assert.Equal(t, adData.PhotoLinks, ad.PhotoLinks)
+		assert.Equal(t, categoryID, ad.CategoryID)
+		assert.Equal(t, ownerID, ad.OwnerID)
+		assertStoredTime(t, start, ad.CreatedAt)
+		assert.Equal(t, ad.CreatedAt, ad.UpdatedAt)
+		assert.Nil(t, ad.DeletedAt)
+	}
+	uncategorized, err := conn.SelectAd(ctx, uncategorizedID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, uncategorized) {
+		assert.Equal(t, "", uncategorized.CategoryID)
+	}
+	missing, err := conn.SelectAd(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	title, photoLinks := "Red bicycle, almost new", []string{"https://example.com/3.jpg"}
+	updated, err := conn.UpdateAd(ctx, adID, models.UpdatingAd{Title: &title, PhotoLinks: &photoLinks})
+	assert.NoError(t, err)
+	if assert.NotNil(t, updated) && assert.NotNil(t, ad) {
+		assert.Equal(t, title, updated.Title)
+		assert.Equal(t, photoLinks, updated.PhotoLinks)
+		assert.Equal(t, adData.Description, updated.Description)
+		assert.Equal(t, adData.Price, updated.Price)
+		assert.Equal(t, ad.CreatedAt, updated.CreatedAt)
+		assertStoredTime(t, ad.CreatedAt, updated.UpdatedAt)
+	}
+	selected, err := conn.SelectAd(ctx, adID)
+	assert.NoError(t, err)
+	assert.Equal(t, updated, selected)
+	updated, err = conn.UpdateAd(ctx, "missing", models.UpdatingAd{Title: &title})
+	assert.NoError(t, err)
+	assert.Nil(t, updated)
+
+	missingCategory, noCategory := "missing", ""
+	_, err = conn.UpdateAd(ctx, uncategorizedID, models.UpdatingAd{CategoryID: &missingCategory})
+	assert.Error(t, err)
+	uncategorized, err = conn.SelectAd(ctx, uncategorizedID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, uncategorized) {
+		assert.Equal(t, "", uncategorized.CategoryID)
+	}
+	updated, err = conn.UpdateAd(ctx, adID, models.UpdatingAd{CategoryID: &noCategory})
+	assert.NoError(t, err)
+	if assert.NotNil(t, updated) {
+		assert.Equal(t, "", updated.CategoryID)
+	}
+
+	count, err := conn.CountAds(ctx, models.AdsFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func testNewAds(t *testing.T, conn db.DatabaseConnection) {
+	ctx := context.Background()
+	ownerID := newUser(t, conn, "owner@example.com")
+	batch := []models.CreatingAd{creatingAd("first", 300), creatingAd("second", 100), creatingAd("third", 200)}
+	ids, err := conn.NewAds(ctx, batch, ownerID)
+	assert.NoError(t, err)
+	if !assert.Len(t, ids, len(batch)) {
+		return
+	}
+	var createdAt time.Time
+	for i, adID := range ids {
+		ad, err := conn.SelectAd(ctx, adID)
+		assert.NoError(t, err)
+		if assert.NotNil(t, ad) {
+			assert.Equal(t, batch[i].Title, ad.Title)
+			if i == 0 {
+				createdAt = ad.CreatedAt
+			}
+			assert.Equal(t, createdAt, ad.CreatedAt)
+		}
+	}
+	empty, err := conn.NewAds(ctx, []models.CreatingAd{}, ownerID)
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func testSortingTies(t *testing.T, conn db.DatabaseConnection) {
+	ctx := context.Background()
+	ownerID := newUser(t, conn, "owner@example.com")
+	// ads of a batch share created_at, so sorting by it has to fall back to ad_id too
+	ids, err := conn.NewAds(ctx, []models.CreatingAd{creatingAd("a", 100), creatingAd("b", 100), creatingAd("c", 50), creatingAd("d", 100)}, ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cheapID := ids[2]
+	var tiedIDs []string
+	for _, adID := range ids {
+		if adID != cheapID {
+			tiedIDs = append(tiedIDs, adID)
+		}
+	}
+	sort.Strings(tiedIDs)
+	reversedTiedIDs := []string{tiedIDs[2], tiedIDs[1], tiedIDs[0]}
+	allIDs := append([]string(nil), ids...)
+	sort.Strings(allIDs)
+	reversedAllIDs := []string{allIDs[3], allIDs[2], allIDs[1], allIDs[0]}
+
+	assert.Equal(t, append([]string{cheapID}, tiedIDs...), adIDs(getAllAds(t, conn, "price", "asc", 1, 10, models.AdsFilter{}, nil)))
+	assert.Equal(t, append(reversedTiedIDs, cheapID), adIDs(getAllAds(t, conn, "price", "desc", 1, 10, models.AdsFilter{}, nil)))
+	assert.Equal(t, allIDs, adIDs(getAllAds(t, conn, "created_at", "asc", 1, 10, models.AdsFilter{}, nil)))
+	assert.Equal(t, reversedAllIDs, adIDs(getAllAds(t, conn, "created_at", "desc", 1, 10, models.AdsFilter{}, nil)))
+
+	// a page boundary inside a group of ties neither skips nor repeats ads
+	var paged []string
+	for page := 1; page <= 4; page++ {
+		paged = append(paged, adIDs(getAllAds(t, conn, "price", "desc", page, 1, models.AdsFilter{}, nil))...)
+	}
+	assert.Equal(t, append(reversedTiedIDs, cheapID), paged)
+}
+
+func testPagination(t *testing.T, conn db.DatabaseConnection) {
+	ctx := context.Background()
+	ads := getAllAds(t, conn, "price", "asc", 1, 10, models.AdsFilter{}, nil)
+	assert.NotNil(t, ads, "empty result is an empty slice")
+	assert.Empty(t, ads)
+	count, err := conn.CountAds(ctx, models.AdsFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	ownerID := newUser(t, conn, "owner@example.com")
+	var ids []string
+	for price := int64(1); price <= 5; price++ {
+		ids = append(ids, newAd(t, conn, creatingAd("ad", price*10), ownerID))
+	}
+	tests := []struct {
+		name     string
+		page     int
+		perPage  int
+		expected []string
+	}{
+		{"First page", 1, 2, ids[0:2]},
+		{"Middle page", 2, 2, ids[2:4]},
+		{"Last partial page", 3, 2, ids[4:5]},
+		{"Page past the end", 4, 2, []string{}},
+		{"Far page past the end", 100, 2, []string{}},
+		{"Page of exact size", 1, 5, ids},
+		{"Page larger than all ads", 1, 50, ids},
+		{"Single ad pages", 5, 1, ids[4:5]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ads := getAllAds(t, conn, "price", "asc", tt.page, tt.perPage, models.AdsFilter{}, nil)
+			assert.NotNil(t, ads)
+			assert.Equal(t, tt.expected, adIDs(ads))
+		})
+	}
+}
+
+func testCursorPagination(t *testing.T, conn db.DatabaseConnection) {
+	ownerID := newUser(t, conn, "owner@example.com")
+	for _, price := range []int64{30, 10, 20, 10, 30, 20, 10} {
+		newAd(t, conn, creatingAd("ad", price), ownerID)
+	}
+	for _, sortBy := range []string{"price", "created_at"} {
+		for _, sortOrder := range []string{"asc", "desc"} {
+			t.Run(sortBy+" "+sortOrder, func(t *testing.T) {
+				expected := adIDs(getAllAds(t, conn, sortBy, sortOrder, 1, 10, models.AdsFilter{}, nil))
+				var walked []string
+				var after *models.AdsCursor
+				page := 1
+				for i := 0; i < 10; i++ {
+					ads := getAllAds(t, conn, sortBy, sortOrder, page, 3, models.AdsFilter{}, after)
+					assert.NotNil(t, ads)
+					if len(ads) == 0 {
+						break
+					}
+					walked = append(walked, adIDs(ads)...)
+					after = cursorOf(ads[len(ads)-1], sortBy, sortOrder)
+					// the page is ignored once there is a cursor
+					page = 3
+				}
+				assert.Equal(t, expected, walked)
+			})
+		}
+	}
+}
+
+func testFilters(t *testing.T, conn db.DatabaseConnection) {
+	ctx := context.Background()
+	ownerID := newUser(t, conn, "owner@example.com")
+	otherOwnerID := newUser(t, conn, "other@example.com")
+	transportID := newCategory(t, conn, "Transport", nil)
+	bicyclesID := newCategory(t, conn, "Bicycles", &transportID)
+	furnitureID := newCategory(t, conn, "Furniture", nil)
+	create := func(title string, price int64, categoryID string, ownerID string) string {
+		// distinct creation times keep the date range filter unambiguous
+		time.Sleep(time.Millisecond)
+		adData := creatingAd(title, price)
+		adData.CategoryID = categoryID
+		return newAd(t, conn, adData, ownerID)
+	}
+	carID := create("Old car", 5000, transportID, ownerID)
+	bicycleID := create("Red bicycle", 300, bicyclesID, ownerID)
+	sofaID := create("Blue sofa", 700, furnitureID, otherOwnerID)
+	plainID := create("Something red", 100, "", otherOwnerID)
+	createdAt := func(adID string) time.Time {
+		ad, err := conn.SelectAd(ctx, adID)
+		if err != nil || ad == nil {
+			t.Fatal("can't select ad", adID, err)
+		}
+		return ad.CreatedAt
+	}
+
+	minPrice, maxPrice := int64(300), int64(700)
+	bicycleCreatedAt, sofaCreatedAt := createdAt(bicycleID), createdAt(sofaID)
+	tests := []struct {
+		name     string
+		filter   models.AdsFilter
+		expected []string
+	}{
+		{"No filter", models.AdsFilter{}, []string{plainID, bicycleID, sofaID, carID}},
+		{"Inclusive price range", models.AdsFilter{MinPrice: &minPrice, MaxPrice: &maxPrice}, []string{bicycleID, sofaID}},
+		{"Inclusive creation date range", models.AdsFilter{CreatedAfter: &bicycleCreatedAt, CreatedBefore: &sofaCreatedAt}, []string{bicycleID, sofaID}},
+		{"Case insensitive query in title or description", models.AdsFilter{Query: "RED"}, []string{plainID, bicycleID}},
+		{"Query matching nothing", models.AdsFilter{Query: "piano"}, []string{}},
+		{"Owner", models.AdsFilter{OwnerID: otherOwnerID}, []string{plainID, sofaID}},
+		{"Category includes descendants", models.AdsFilter{CategoryID: transportID}, []string{bicycleID, carID}},
+		{"Leaf category", models.AdsFilter{CategoryID: bicyclesID}, []string{bicycleID}},
+		{"Unknown category", models.AdsFilter{CategoryID: "missing"}, []string{}},
+		{"Combined", models.AdsFilter{MinPrice: &minPrice, Query: "red", CategoryID: transportID}, []string{bicycleID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, adIDs(getAllAds(t, conn, "price", "asc", 1, 10, tt.filter, nil)))
+			count, err := conn.CountAds(ctx, tt.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(tt.expected)), count)
+		})
+	}
+}
+
+func testSearch(t *testing.T, conn db.DatabaseConnection) {
+	ctx := context.Background()
+	found, err := conn.SearchAds(ctx, "bicycle", 1, 10, false)
+	assert.NoError(t, err)
+	assert.NotNil(t, found, "empty result is an empty slice")
+	assert.Empty(t, found)
+
+	ownerID := newUser(t, conn, "owner@example.com")
+	bicycleID := newAd(t, conn, creatingAd("Red bicycle", 100), ownerID)
+	childBicycleID := newAd(t, conn, models.CreatingAd{Title: "Small bicycle", Description: "A bicycle for kids", Price: 50, PhotoLinks: []string{"https://example.com/1.jpg"}}, ownerID)
+	deletedID := newAd(t, conn, creatingAd("Old bicycle", 10), ownerID)
+	newAd(t, conn, creatingAd("Blue sofa", 200), ownerID)
+	if _, err := conn.DeleteAd(ctx, deletedID); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err = conn.SearchAds(ctx, "bicycle", 1, 10, true)
+	assert.NoError(t, err)
+	var foundIDs []string
+	for _, ad := range found {
+		foundIDs = append(foundIDs, ad.AdID)
+		assert.Greater(t, ad.Rank, 0.0)
+		assert.Contains(t, ad.Snippet, "<b>")
+	}
+	assert.ElementsMatch(t, []string{bicycleID, childBicycleID}, foundIDs)
+	if len(found) == 2 {
+		assert.GreaterOrEqual(t, found[0].Rank, found[1].Rank)
+	}
+
+	found, err = conn.SearchAds(ctx, "bicycle", 1, 10, false)
+	assert.NoError(t, err)
+	for _, ad := range found {
+		assert.Equal(t, "", ad.Snippet)
+	}
+	first, err := conn.SearchAds(ctx, "bicycle", 1, 1, false)
+	assert.NoError(t, err)
+	second, err := conn.SearchAds(ctx, "bicycle", 2, 1, false)
+	assert.NoError(t, err)
+	if assert.Len(t, first, 1) && assert.Len(t, second, 1) {
+		assert.ElementsMatch(t, []string{bicycleID, childBicycleID}, []string{first[0].AdID, second[0].AdID})
+	}
+	found, err = conn.SearchAds(ctx, "bicycle", 3, 1, false)
+	assert.NoError(t, err)
+	assert.NotNil(t, found)
+	assert.Empty(t, found)
+}
+
+func testSoftDelete(t *testing.T, conn db.DatabaseConnection) {
+	ctx := context.Background()
+	ownerID := newUser(t, conn, "owner@example.com")
+	adID := newAd(t, conn, creatingAd("Red bicycle", 100), ownerID)
+	keptID := newAd(t, conn, creatingAd("Blue sofa", 200), ownerID)
+	ad, err := conn.SelectAd(ctx, adID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := conn.DeleteAd(ctx, adID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = conn.DeleteAd(ctx, adID)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+	deleted, err = conn.DeleteAd(ctx, "missing")
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+	selected, err := conn.SelectAd(ctx, adID)
+	assert.NoError(t, err)
+	assert.Nil(t, selected)
+	title := "updated"
+	updated, err := conn.UpdateAd(ctx, adID, models.UpdatingAd{Title: &title})
+	assert.NoError(t, err)
+	assert.Nil(t, updated)
+	assert.Equal(t, []string{keptID}, adIDs(getAllAds(t, conn, "price", "asc", 1, 10, models.AdsFilter{}, nil)))
+	count, err := conn.CountAds(ctx, models.AdsFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	restored, err := conn.RestoreAd(ctx, adID)
+	assert.NoError(t, err)
+	assert.True(t, restored)
+	restored, err = conn.RestoreAd(ctx, adID)
+	assert.NoError(t, err)
+	assert.False(t, restored)
+	restored, err = conn.RestoreAd(ctx, keptID)
+	assert.NoError(t, err)
+	assert.False(t, restored)
+	selected, err = conn.SelectAd(ctx, adID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, selected) {
+		assert.Nil(t, selected.DeletedAt)
+		assert.Equal(t, ad.CreatedAt, selected.CreatedAt)
+		assertStoredTime(t, ad.CreatedAt, selected.UpdatedAt)
+	}
+
+	if _, err := conn.DeleteAd(ctx, adID); err != nil {
+		t.Fatal(err)
+	}
+	purged, err := conn.PurgeDeletedAds(ctx, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), purged)
+	purged, err = conn.PurgeDeletedAds(ctx, time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	restored, err = conn.RestoreAd(ctx, adID)
+	assert.NoError(t, err)
+	assert.False(t, restored)
+	selected, err = conn.SelectAd(ctx, keptID)
+	assert.NoError(t, err)
+	assert.NotNil(t, selected)
+}
+
+func testCategories(t *testing.T, conn db.DatabaseConnection) {
+	ctx := context.Background()
+	categories, err := conn.GetAllCategories(ctx)
+	assert.NoError(t, err)
+	assert.NotNil(t, categories, "empty result is an empty slice")
+	assert.Empty(t, categories)
+
+	transportID := newCategory(t, conn, "Transport", nil)
+	bicyclesID := newCategory(t, conn, "Bicycles", &transportID)
+	furnitureID := newCategory(t, conn, "Furniture", nil)
+	category, err := conn.SelectCategory(ctx, bicyclesID)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Category{CategoryID: bicyclesID, ParentID: &transportID, Name: "Bicycles"}, category)
+	category, err = conn.SelectCategory(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, category)
+
+	categories, err = conn.GetAllCategories(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Category{
+		{CategoryID: bicyclesID, ParentID: &transportID, Name: "Bicycles"},
+		{CategoryID: furnitureID, Name: "Furniture"},
+		{CategoryID: transportID, Name: "Transport"},
+	}, categories)
+
+	updated, err := conn.UpdateCategory(ctx, bicyclesID, models.CreatingCategory{Name: "Bikes", ParentID: &furnitureID})
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Category{CategoryID: bicyclesID, ParentID: &furnitureID, Name: "Bikes"}, updated)
+	updated, err = conn.UpdateCategory(ctx, "missing", models.CreatingCategory{Name: "Bikes"})
+	assert.NoError(t, err)
+	assert.Nil(t, updated)
+
+	deleted, err := conn.DeleteCategory(ctx, furnitureID)
+	assert.Equal(t, db.ErrCategoryNotEmpty, err)
+	assert.False(t, deleted)
+	ownerID := newUser(t, conn, "owner@example.com")
+	adData := creatingAd("Red bicycle", 100)
+	adData.CategoryID = bicyclesID
+	adID := newAd(t, conn, adData, ownerID)
+	if _, err := conn.DeleteAd(ctx, adID); err != nil {
+		t.Fatal(err)
+	}
+	// soft deleted ads still reference the category
+	deleted, err = conn.DeleteCategory(ctx, bicyclesID)
+	assert.Equal(t, db.ErrCategoryNotEmpty, err)
+	assert.False(t, deleted)
+
+	deleted, err = conn.DeleteCategory(ctx, transportID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = conn.DeleteCategory(ctx, transportID)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+	category, err = conn.SelectCategory(ctx, transportID)
+	assert.NoError(t, err)
+	assert.Nil(t, category)
+}
+
+func testUsers(t *testing.T, conn db.DatabaseConnection) {
+	ctx := context.Background()
+	start := time.Now()
+	userID, err := conn.NewUser(ctx, models.CreatingUser{Name: "Ivan", Email: "Ivan@Example.com"})
+	assert.NoError(t, err)
+	user, err := conn.SelectUser(ctx, userID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, user) {
+		assert.Equal(t, userID, user.UserID)
+		assert.Equal(t, "Ivan", user.Name)
+		assert.Equal(t, "Ivan@Example.com", user.Email)
+		assertStoredTime(t, start, user.CreatedAt)
+	}
+	user, err = conn.SelectUser(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, user)
+
+	_, err = conn.NewUser(ctx, models.CreatingUser{Name: "Ivan", Email: "ivan@example.COM"})
+	assert.Equal(t, db.ErrEmailTaken, err)
+	otherID, err := conn.NewUser(ctx, models.CreatingUser{Name: "Ivan", Email: "ivan@example.org"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, userID, otherID)
+}
+
+func testIdempotencyKeys(t *testing.T, conn db.DatabaseConnection) {
+	ctx := context.Background()
+	ownerID := newUser(t, conn, "owner@example.com")
+	otherOwnerID := newUser(t, conn, "other@example.com")
+	expiresAt := time.Now().Add(time.Hour)
+	stored := expiresAt.UTC().Truncate(time.Microsecond)
+
+	record, err := conn.ReserveIdempotencyKey(ctx, ownerID, "key", "hash", expiresAt)
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+	record, err = conn.ReserveIdempotencyKey(ctx, ownerID, "key", "other", expiresAt)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.IdempotencyRecord{OwnerID: ownerID, Key: "key", RequestHash: "hash", ExpiresAt: stored}, record)
+	record, err = conn.ReserveIdempotencyKey(ctx, otherOwnerID, "key", "other", expiresAt)
+	assert.NoError(t, err)
+	assert.Nil(t, record, "keys are scoped by owner")
+
+	assert.NoError(t, conn.CompleteIdempotencyKey(ctx, ownerID, "key", 201, []byte(`{"ad_id":"1"}`)))
+	assert.NoError(t, conn.ReleaseIdempotencyKey(ctx, ownerID, "key"))
+	record, err = conn.ReserveIdempotencyKey(ctx, ownerID, "key", "other", expiresAt)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.IdempotencyRecord{OwnerID: ownerID, Key: "key", RequestHash: "hash", ResponseCode: 201, ResponseBody: []byte(`{"ad_id":"1"}`), ExpiresAt: stored}, record)
+	assert.NoError(t, conn.CompleteIdempotencyKey(ctx, ownerID, "missing", 201, nil))
+	assert.NoError(t, conn.ReleaseIdempotencyKey(ctx, ownerID, "missing"))
+
+	assert.NoError(t, conn.ReleaseIdempotencyKey(ctx, otherOwnerID, "key"))
+	record, err = conn.ReserveIdempotencyKey(ctx, otherOwnerID, "key", "hash", expiresAt)
+	assert.NoError(t, err)
+	assert.Nil(t, record, "released key can be reserved again")
+
+	record, err = conn.ReserveIdempotencyKey(ctx, ownerID, "expired", "hash", time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+	record, err = conn.ReserveIdempotencyKey(ctx, ownerID, "expired", "other", expiresAt)
+	assert.NoError(t, err)
+	assert.Nil(t, record, "expired key can be reserved again")
+
+	if _, err := conn.ReserveIdempotencyKey(ctx, ownerID, "stale", "hash", time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	purged, err := conn.PurgeIdempotencyKeys(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	purged, err = conn.PurgeIdempotencyKeys(ctx, expiresAt.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+}
+
+func testConcurrentAds(t *testing.T, conn db.DatabaseConnection) {
+	ctx := context.Background()
+	ownerID := newUser(t, conn, "owner@example.com")
+	const workers = 16
+	var wg sync.WaitGroup
+	errs := make(chan error, 3*workers)
+	ids := make(chan string, 2*workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			adID, err := conn.NewAd(ctx, creatingAd("ad", int64(i+1)), ownerID)
+			errs <- err
+			ids <- adID
+			batch, err := conn.NewAds(ctx, []models.CreatingAd{creatingAd("batch", int64(i+1))}, ownerID)
+			errs <- err
+			for _, adID := range batch {
+				ids <- adID
+			}
+			_, err = conn.GetAllAds(ctx, "price", "desc", 1, 10, models.AdsFilter{}, nil)
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	close(ids)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	unique := map[string]bool{}
+	for adID := range ids {
+		unique[adID] = true
+	}
+	assert.Len(t, unique, 2*workers)
+	count, err := conn.CountAds(ctx, models.AdsFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2*workers), count)
+}
+
+func testConcurrentUsers(t *testing.T, conn db.DatabaseConnection) {
+	ctx := context.Background()
+	const workers = 16
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := conn.NewUser(ctx, models.CreatingUser{Name: "user", Email: "same@example.com"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+		} else {
+			assert.Equal(t, db.ErrEmailTaken, err)
+		}
+	}
+	assert.Equal(t, 1, created)
+}
+
+func testConcurrentIdempotencyKeys(t *testing.T, conn db.DatabaseConnection) {
+	ctx := context.Background()
+	ownerID := newUser(t, conn, "owner@example.com")
+	expiresAt := time.Now().Add(time.Hour)
+	const workers = 16
+	var wg sync.WaitGroup
+	records := make(chan *models.IdempotencyRecord, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record, err := conn.ReserveIdempotencyKey(ctx, ownerID, "key", "hash", expiresAt)
+			assert.NoError(t, err)
+			records <- record
+		}()
+	}
+	wg.Wait()
+	close(records)
+	reserved := 0
+	for record := range records {
+		if record == nil {
+			reserved++
+		} else {
+			assert.Equal(t, "hash", record.RequestHash)
+		}
+	}
+	assert.Equal(t, 1, reserved)
+}
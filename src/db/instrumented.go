@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"adv-backend-trainee-assignment/src/models"
+)
+
+type Call struct {
+	Method string
+	// Rows is the number of ads NewAd and NewAds write, zero for other methods.
+	Rows int
+}
+
+// Hook is called before every call to the wrapped DatabaseConnection. It may return a derived context
+// for the call, and returns the function to call with the call's error once it's done.
+type Hook func(ctx context.Context, call Call) (context.Context, func(err error))
+
+type instrumentedDB struct {
+	next  DatabaseConnection
+	hooks []Hook
+}
+
+// Instrumented runs hooks around every call to next except Close. Hooks are started in order and finished in reverse.
+func Instrumented(next DatabaseConnection, hooks ...Hook) DatabaseConnection {
+	return &instrumentedDB{next: next, hooks: hooks}
+}
+
+func (instrumented *instrumentedDB) start(ctx context.Context, call Call) (context.Context, func(err error)) {
+	finishes := make([]func(err error), len(instrumented.hooks))
+	for i, hook := range instrumented.hooks {
+		ctx, finishes[i] = hook(ctx, call)
+	}
+	return ctx, func(err error) {
+		for i := len(finishes) - 1; i >= 0; i-- {
+			finishes[i](err)
+		}
+	}
+}
+
+func (instrumented *instrumentedDB) NewAd(ctx context.Context, adData models.CreatingAd, ownerID string) (string, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "NewAd", Rows: 1})
+	res, err := instrumented.next.NewAd(ctx, adData, ownerID)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) NewAds(ctx context.Context, ads []models.CreatingAd, ownerID string) ([]string, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "NewAds", Rows: len(ads)})
+	res, err := instrumented.next.NewAds(ctx, ads, ownerID)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) SelectAd(ctx context.Context, adID string) (*models.DbAd, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "SelectAd"})
+	res, err := instrumented.next.SelectAd(ctx, adID)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) GetAllAds(ctx context.Context, sortBy string, sortOrder string, page int, perPage int, filter models.AdsFilter, after *models.AdsCursor) ([]*models.DbAd, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "GetAllAds"})
+	res, err := instrumented.next.GetAllAds(ctx, sortBy, sortOrder, page, perPage, filter, after)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) CountAds(ctx context.Context, filter models.AdsFilter) (int64, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "CountAds"})
+	res, err := instrumented.next.CountAds(ctx, filter)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) SearchAds(ctx context.Context, query string, page int, perPage int, withSnippets bool) ([]*models.FoundAd, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "SearchAds"})
+	res, err := instrumented.next.SearchAds(ctx, query, page, perPage, withSnippets)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) UpdateAd(ctx context.Context, adID string, adData models.UpdatingAd) (*models.DbAd, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "UpdateAd"})
+	res, err := instrumented.next.UpdateAd(ctx, adID, adData)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) DeleteAd(ctx context.Context, adID string) (bool, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "DeleteAd"})
+	res, err := instrumented.next.DeleteAd(ctx, adID)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) RestoreAd(ctx context.Context, adID string) (bool, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "RestoreAd"})
+	res, err := instrumented.next.RestoreAd(ctx, adID)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) PurgeDeletedAds(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "PurgeDeletedAds"})
+	res, err := instrumented.next.PurgeDeletedAds(ctx, deletedBefore)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) NewCategory(ctx context.Context, category models.CreatingCategory) (string, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "NewCategory"})
+	res, err := instrumented.next.NewCategory(ctx, category)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) SelectCategory(ctx context.Context, categoryID string) (*models.Category, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "SelectCategory"})
+	res, err := instrumented.next.SelectCategory(ctx, categoryID)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) GetAllCategories(ctx context.Context) ([]*models.Category, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "GetAllCategories"})
+	res, err := instrumented.next.GetAllCategories(ctx)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) UpdateCategory(ctx context.Context, categoryID string, category models.CreatingCategory) (*models.Category, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "UpdateCategory"})
+	res, err := instrumented.next.UpdateCategory(ctx, categoryID, category)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) DeleteCategory(ctx context.Context, categoryID string) (bool, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "DeleteCategory"})
+	res, err := instrumented.next.DeleteCategory(ctx, categoryID)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) NewUser(ctx context.Context, user models.CreatingUser) (string, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "NewUser"})
+	res, err := instrumented.next.NewUser(ctx, user)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) SelectUser(ctx context.Context, userID string) (*models.User, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "SelectUser"})
+	res, err := instrumented.next.SelectUser(ctx, userID)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) ReserveIdempotencyKey(ctx context.Context, ownerID string, key string, requestHash string, expiresAt time.Time) (*models.IdempotencyRecord, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "ReserveIdempotencyKey"})
+	res, err := instrumented.next.ReserveIdempotencyKey(ctx, ownerID, key, requestHash, expiresAt)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) CompleteIdempotencyKey(ctx context.Context, ownerID string, key string, responseCode int, responseBody []byte) error {
+	ctx, finish := instrumented.start(ctx, Call{Method: "CompleteIdempotencyKey"})
+	err := instrumented.next.CompleteIdempotencyKey(ctx, ownerID, key, responseCode, responseBody)
+	finish(err)
+	return err
+}
+
+func (instrumented *instrumentedDB) ReleaseIdempotencyKey(ctx context.Context, ownerID string, key string) error {
+	ctx, finish := instrumented.start(ctx, Call{Method: "ReleaseIdempotencyKey"})
+	err := instrumented.next.ReleaseIdempotencyKey(ctx, ownerID, key)
+	finish(err)
+	return err
+}
+
+func (instrumented *instrumentedDB) PurgeIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	ctx, finish := instrumented.start(ctx, Call{Method: "PurgeIdempotencyKeys"})
+	res, err := instrumented.next.PurgeIdempotencyKeys(ctx, expiredBefore)
+	finish(err)
+	return res, err
+}
+
+func (instrumented *instrumentedDB) Ping(ctx context.Context) error {
+	ctx, finish := instrumented.start(ctx, Call{Method: "Ping"})
+	err := instrumented.next.Ping(ctx)
+	finish(err)
+	return err
+}
+
+func (instrumented *instrumentedDB) Close() error {
+	return instrumented.next.Close()
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type hookKey struct{}
+
+func TestInstrumented(t *testing.T) {
+	var events []string
+	var calls []Call
+	hook := func(name string) Hook {
+		return func(ctx context.Context, call Call) (context.Context, func(err error)) {
+			events = append(events, "start "+name)
+			if name == "outer" {
+				calls = append(calls, call)
+				ctx = context.WithValue(ctx, hookKey{}, name)
+			} else {
+				assert.Equal(t, "outer", ctx.Value(hookKey{}))
+			}
+			return ctx, func(err error) {
+				events = append(events, "finish "+name)
+				if err != nil {
+					events = append(events, name+" got error")
+				}
+			}
+		}
+	}
+	mock := NewMockedDBManager()
+	instrumented := Instrumented(mock, hook("outer"), hook("inner"))
+	ctx := context.Background()
+
+	_, err := instrumented.NewAds(ctx, []models.CreatingAd{{Title: "title"}, {Title: "title"}}, "owner")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"start outer", "start inner", "finish inner", "finish outer"}, events)
+	assert.Equal(t, []Call{{Method: "NewAds", Rows: 2}}, calls)
+
+	events, calls = nil, nil
+	mock.SetLatency(time.Hour)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = instrumented.SelectAd(canceled, "ad")
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, []string{"start outer", "start inner", "finish inner", "inner got error", "finish outer", "outer got error"}, events)
+	assert.Equal(t, []Call{{Method: "SelectAd"}}, calls)
+
+	events = nil
+	assert.NoError(t, instrumented.Close())
+	assert.Empty(t, events)
+}
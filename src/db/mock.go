@@ -3,145 +3,785 @@ package db
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"adv-backend-trainee-assignment/src/models"
 	"github.com/google/uuid"
 )
 
+// mockIdempotencyID keeps owner id and key apart, so no pair of them can collide.
+type mockIdempotencyID struct {
+	ownerID string
+	key     string
+}
+
 type MockedDBManager struct {
-	data map[string][]byte
-	ctx  context.Context
-	sync sync.Mutex
+	data            map[string][]byte
+	categories      map[string][]byte
+	users           map[string][]byte
+	idempotencyKeys map[mockIdempotencyID][]byte
+	latency         time.Duration
+	sync            sync.Mutex
 }
 
 func NewMockedDBManager() *MockedDBManager {
-	return &MockedDBManager{data: make(map[string][]byte), ctx: context.Background()}
+	return &MockedDBManager{data: make(map[string][]byte), categories: make(map[string][]byte), users: make(map[string][]byte), idempotencyKeys: make(map[mockIdempotencyID][]byte)}
+}
+
+// SetLatency makes every call wait before touching data, so tests can exercise deadlines.
+func (mock *MockedDBManager) SetLatency(latency time.Duration) {
+	mock.latency = latency
+}
+
+func (mock *MockedDBManager) wait(ctx context.Context) error {
+	if mock.latency <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(mock.latency)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (mock *MockedDBManager) Ping(ctx context.Context) error {
+	return mock.wait(ctx)
 }
 
 func (mock *MockedDBManager) Close() error {
 	mock.data = map[string][]byte{}
+	mock.categories = map[string][]byte{}
+	mock.users = map[string][]byte{}
+	mock.idempotencyKeys = map[mockIdempotencyID][]byte{}
 	return nil
 }
 
-func (mock *MockedDBManager) NewAd(adData models.CreatingAd) (string, error) {
+func marshalNewAd(adData models.CreatingAd, ownerID string, createdAt time.Time) (string, []byte, error) {
 	adID := uuid.New().String()
 	marshalledPhotoLinks, err := json.Marshal(adData.PhotoLinks)
+	if err != nil {
+		return "", nil, err
+	}
+	now := strconv.FormatInt(createdAt.UnixNano(), 10)
+	marshalledAd, err := json.Marshal(map[string]string{
+		"ad_id":       adID,
+		"title":       adData.Title,
+		"description": adData.Description,
+		"price":       strconv.FormatInt(adData.Price, 10),
+		"photo_links": string(marshalledPhotoLinks),
+		"category_id": adData.CategoryID,
+		"owner_id":    ownerID,
+		"created_at":  now,
+		"updated_at":  now,
+	})
+	return adID, marshalledAd, err
+}
+
+func (mock *MockedDBManager) NewAd(ctx context.Context, adData models.CreatingAd, ownerID string) (string, error) {
+	if err := mock.wait(ctx); err != nil {
+		return "", err
+	}
+	adID, marshalledAd, err := marshalNewAd(adData, ownerID, currentTime())
 	if err != nil {
 		return "", err
-	} else {
-		mock.sync.Lock()
-		mock.data[adID], err = json.Marshal(map[string]string{
-			"ad_id":       adID,
-			"title":       adData.Title,
-			"description": adData.Description,
-			"price":       strconv.FormatInt(adData.Price, 10),
-			"photo_links": string(marshalledPhotoLinks),
-			"created_at":  strconv.FormatInt(time.Now().UTC().UnixNano(), 10),
-		})
-		mock.sync.Unlock()
+	}
+	mock.sync.Lock()
+	mock.data[adID] = marshalledAd
+	mock.sync.Unlock()
+	return adID, nil
+}
+
+func (mock *MockedDBManager) NewAds(ctx context.Context, ads []models.CreatingAd, ownerID string) ([]string, error) {
+	if err := mock.wait(ctx); err != nil {
+		return nil, err
+	}
+	adIDs := make([]string, len(ads))
+	marshalledAds := make([][]byte, len(ads))
+	// ads of a batch are inserted at once, so they share created_at as in the other backends
+	now := currentTime()
+	for i, adData := range ads {
+		var err error
+		adIDs[i], marshalledAds[i], err = marshalNewAd(adData, ownerID, now)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		return adID, nil
 	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	for i, adID := range adIDs {
+		mock.data[adID] = marshalledAds[i]
+	}
+	return adIDs, nil
 }
 
-func (mock *MockedDBManager) SelectAd(adID string) (*models.DbAd, error) {
+func unmarshalAd(raw []byte) (*models.DbAd, error) {
 	var rawData map[string]string
+	err := json.Unmarshal(raw, &rawData)
+	if err != nil {
+		return nil, err
+	}
+	var data = &models.DbAd{
+		AdID:        rawData["ad_id"],
+		Title:       rawData["title"],
+		Description: rawData["description"],
+		CategoryID:  rawData["category_id"],
+		OwnerID:     rawData["owner_id"],
+	}
+	data.Price, err = strconv.ParseInt(rawData["price"], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	data.CreatedAt, err = parseMockedTime(rawData["created_at"])
+	if err != nil {
+		return nil, err
+	}
+	data.UpdatedAt, err = parseMockedTime(rawData["updated_at"])
+	if err != nil {
+		return nil, err
+	}
+	if rawData["deleted_at"] != "" {
+		deletedAt, err := parseMockedTime(rawData["deleted_at"])
+		if err != nil {
+			return nil, err
+		}
+		data.DeletedAt = &deletedAt
+	}
+	err = json.Unmarshal([]byte(rawData["photo_links"]), &data.PhotoLinks)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func parseMockedTime(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	rawTime, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, rawTime).UTC(), nil
+}
+
+func (mock *MockedDBManager) SelectAd(ctx context.Context, adID string) (*models.DbAd, error) {
+	if err := mock.wait(ctx); err != nil {
+		return nil, err
+	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
 	if val, ok := mock.data[adID]; !ok {
 		return nil, nil
 	} else {
-		err := json.Unmarshal(val, &rawData)
+		data, err := unmarshalAd(val)
+		if err != nil || data.DeletedAt != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+}
+
+func matchesAdsFilter(data *models.DbAd, filter models.AdsFilter, categoryTree map[string]bool) bool {
+	if data.DeletedAt != nil {
+		return false
+	}
+	if categoryTree != nil && !categoryTree[data.CategoryID] {
+		return false
+	}
+	if filter.OwnerID != "" && data.OwnerID != filter.OwnerID {
+		return false
+	}
+	if filter.MinPrice != nil && data.Price < *filter.MinPrice {
+		return false
+	}
+	if filter.MaxPrice != nil && data.Price > *filter.MaxPrice {
+		return false
+	}
+	if filter.CreatedAfter != nil && data.CreatedAt.Before(*filter.CreatedAfter) {
+		return false
+	}
+	if filter.CreatedBefore != nil && data.CreatedAt.After(*filter.CreatedBefore) {
+		return false
+	}
+	if filter.Query != "" {
+		query := strings.ToLower(filter.Query)
+		if !strings.Contains(strings.ToLower(data.Title), query) && !strings.Contains(strings.ToLower(data.Description), query) {
+			return false
+		}
+	}
+	return true
+}
+
+func adSortKey(data *models.DbAd, sortBy string) int64 {
+	if sortBy == "price" {
+		return data.Price
+	}
+	return data.CreatedAt.UnixNano()
+}
+
+func adLess(a, b *models.DbAd, sortBy string, sortOrder string) bool {
+	aKey, bKey := adSortKey(a, sortBy), adSortKey(b, sortBy)
+	if aKey == bKey {
+		if sortOrder == "asc" {
+			return a.AdID < b.AdID
+		}
+		return a.AdID > b.AdID
+	}
+	if sortOrder == "asc" {
+		return aKey < bKey
+	}
+	return aKey > bKey
+}
+
+func (mock *MockedDBManager) GetAllAds(ctx context.Context, sortBy string, sortOrder string, page int, perPage int, filter models.AdsFilter, after *models.AdsCursor) ([]*models.DbAd, error) {
+	if err := mock.wait(ctx); err != nil {
+		return nil, err
+	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	categoryTree, err := mock.categoryTree(filter.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	raw := make([]*models.DbAd, 0, len(mock.data))
+	var cursorAd *models.DbAd
+	if after != nil {
+		cursorAd = &models.DbAd{AdID: after.AdID, Price: after.Price, CreatedAt: after.CreatedAt}
+	}
+	for _, v := range mock.data {
+		data, err := unmarshalAd(v)
 		if err != nil {
 			return nil, err
-		} else {
-			var data = &models.DbAd{
-				AdID:        rawData["ad_id"],
-				Title:       rawData["title"],
-				Description: rawData["description"],
-			}
+		}
+		if matchesAdsFilter(data, filter, categoryTree) && (cursorAd == nil || adLess(cursorAd, data, sortBy, sortOrder)) {
+			raw = append(raw, data)
+		}
+	}
+	offset := (page - 1) * perPage
+	if after != nil {
+		offset = 0
+	}
+	limit := len(raw) - offset
+	if limit < 1 || offset < 0 {
+		return []*models.DbAd{}, nil
+	}
+	sort.Slice(raw, func(i, j int) bool {
+		return adLess(raw[i], raw[j], sortBy, sortOrder)
+	})
+	if limit > perPage {
+		limit = perPage
+	}
+	return raw[offset : offset+limit], nil
+}
+
+func (mock *MockedDBManager) CountAds(ctx context.Context, filter models.AdsFilter) (int64, error) {
+	if err := mock.wait(ctx); err != nil {
+		return 0, err
+	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	categoryTree, err := mock.categoryTree(filter.CategoryID)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	for _, v := range mock.data {
+		data, err := unmarshalAd(v)
+		if err != nil {
+			return 0, err
+		}
+		if matchesAdsFilter(data, filter, categoryTree) {
+			count++
+		}
+	}
+	return count, nil
+}
 
-			data.Price, err = strconv.ParseInt(rawData["price"], 10, 64)
+func (mock *MockedDBManager) modifyAd(adID string, modify func(rawData map[string]string) (bool, error)) (*models.DbAd, error) {
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	val, ok := mock.data[adID]
+	if !ok {
+		return nil, nil
+	}
+	var rawData map[string]string
+	err := json.Unmarshal(val, &rawData)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err = modify(rawData); !ok || err != nil {
+		return nil, err
+	}
+	val, err = json.Marshal(rawData)
+	if err != nil {
+		return nil, err
+	}
+	mock.data[adID] = val
+	return unmarshalAd(val)
+}
+
+func (mock *MockedDBManager) UpdateAd(ctx context.Context, adID string, adData models.UpdatingAd) (*models.DbAd, error) {
+	if err := mock.wait(ctx); err != nil {
+		return nil, err
+	}
+	return mock.modifyAd(adID, func(rawData map[string]string) (bool, error) {
+		if rawData["deleted_at"] != "" {
+			return false, nil
+		}
+		if adData.Title != nil {
+			rawData["title"] = *adData.Title
+		}
+		if adData.Description != nil {
+			rawData["description"] = *adData.Description
+		}
+		if adData.Price != nil {
+			rawData["price"] = strconv.FormatInt(*adData.Price, 10)
+		}
+		if adData.PhotoLinks != nil {
+			marshalledPhotoLinks, err := json.Marshal(*adData.PhotoLinks)
 			if err != nil {
-				return nil, err
+				return false, err
 			}
-			rawCreatedAt, err := strconv.ParseInt(rawData["created_at"], 10, 64)
-			if err != nil {
-				return nil, err
+			rawData["photo_links"] = string(marshalledPhotoLinks)
+		}
+		if adData.CategoryID != nil {
+			if _, ok := mock.categories[*adData.CategoryID]; *adData.CategoryID != "" && !ok {
+				return false, fmt.Errorf("category %q doesn't exist", *adData.CategoryID)
 			}
-			data.CreatedAt = time.Unix(rawCreatedAt/1000000000, rawCreatedAt%1000000000)
-			err = json.Unmarshal([]byte(rawData["photo_links"]), &data.PhotoLinks)
-			if err != nil {
-				return nil, err
+			rawData["category_id"] = *adData.CategoryID
+		}
+		rawData["updated_at"] = strconv.FormatInt(currentTime().UnixNano(), 10)
+		return true, nil
+	})
+}
+
+func (mock *MockedDBManager) DeleteAd(ctx context.Context, adID string) (bool, error) {
+	if err := mock.wait(ctx); err != nil {
+		return false, err
+	}
+	data, err := mock.modifyAd(adID, func(rawData map[string]string) (bool, error) {
+		if rawData["deleted_at"] != "" {
+			return false, nil
+		}
+		rawData["deleted_at"] = strconv.FormatInt(currentTime().UnixNano(), 10)
+		return true, nil
+	})
+	return data != nil, err
+}
+
+func (mock *MockedDBManager) RestoreAd(ctx context.Context, adID string) (bool, error) {
+	if err := mock.wait(ctx); err != nil {
+		return false, err
+	}
+	data, err := mock.modifyAd(adID, func(rawData map[string]string) (bool, error) {
+		if rawData["deleted_at"] == "" {
+			return false, nil
+		}
+		delete(rawData, "deleted_at")
+		rawData["updated_at"] = strconv.FormatInt(currentTime().UnixNano(), 10)
+		return true, nil
+	})
+	return data != nil, err
+}
+
+func (mock *MockedDBManager) PurgeDeletedAds(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	if err := mock.wait(ctx); err != nil {
+		return 0, err
+	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	var purged int64
+	for adID, val := range mock.data {
+		data, err := unmarshalAd(val)
+		if err != nil {
+			return purged, err
+		}
+		if data.DeletedAt != nil && data.DeletedAt.Before(deletedBefore) {
+			delete(mock.data, adID)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func countTokens(tokens []string, token string) int {
+	count := 0
+	for _, tmp := range tokens {
+		if tmp == token {
+			count++
+		}
+	}
+	return count
+}
+
+func highlightTokens(text string, queryTokens []string) string {
+	words := strings.Fields(text)
+	firstMatch := -1
+	for i, word := range words {
+		for _, wordToken := range tokenize(word) {
+			if countTokens(queryTokens, wordToken) > 0 {
+				words[i] = "<b>" + word + "</b>"
+				if firstMatch == -1 {
+					firstMatch = i
+				}
+				break
 			}
-			return data, nil
 		}
 	}
+	start := firstMatch - 5
+	if start < 0 {
+		start = 0
+	}
+	end := start + 35
+	if end > len(words) {
+		end = len(words)
+	}
+	return strings.Join(words[start:end], " ")
 }
 
-func (mock *MockedDBManager) GetAllAds(sortBy string, sortOrder string, page int, perPage int) ([]*models.DbAd, error) {
-	offset := (page - 1) * perPage
-	limit := len(mock.data) - offset
-	if limit < 1 || offset < 0 {
-		return []*models.DbAd{}, nil
+// matchAd returns nil unless the ad contains every query token. Title matches weigh more than description ones.
+func matchAd(data *models.DbAd, queryTokens []string, withSnippet bool) *models.FoundAd {
+	if len(queryTokens) == 0 {
+		return nil
 	}
-	raw := make([]*models.DbAd, len(mock.data))
-	i := 0
-	var rawData map[string]string
+	titleTokens, descriptionTokens := tokenize(data.Title), tokenize(data.Description)
+	rank := 0.0
+	for _, token := range queryTokens {
+		matches := float64(countTokens(titleTokens, token)) + 0.4*float64(countTokens(descriptionTokens, token))
+		if matches == 0 {
+			return nil
+		}
+		rank += matches
+	}
+	found := &models.FoundAd{DbAd: *data, Rank: rank / float64(len(titleTokens)+len(descriptionTokens))}
+	if withSnippet {
+		found.Snippet = highlightTokens(data.Title+" "+data.Description, queryTokens)
+	}
+	return found
+}
+
+func sortFoundAds(found []*models.FoundAd) {
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Rank == found[j].Rank {
+			return found[i].AdID < found[j].AdID
+		}
+		return found[i].Rank > found[j].Rank
+	})
+}
+
+func (mock *MockedDBManager) SearchAds(ctx context.Context, query string, page int, perPage int, withSnippets bool) ([]*models.FoundAd, error) {
+	if err := mock.wait(ctx); err != nil {
+		return nil, err
+	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	queryTokens := tokenize(query)
+	var raw []*models.FoundAd
 	for _, v := range mock.data {
-		rawData = map[string]string{}
-		err := json.Unmarshal(v, &rawData)
+		data, err := unmarshalAd(v)
 		if err != nil {
 			return nil, err
 		}
-		var data = &models.DbAd{
-			AdID:        rawData["ad_id"],
-			Title:       rawData["title"],
-			Description: rawData["description"],
+		if data.DeletedAt != nil {
+			continue
+		}
+		if found := matchAd(data, queryTokens, withSnippets); found != nil {
+			raw = append(raw, found)
 		}
-		data.Price, err = strconv.ParseInt(rawData["price"], 10, 64)
+	}
+	sortFoundAds(raw)
+	offset := (page - 1) * perPage
+	if offset < 0 || offset >= len(raw) {
+		return []*models.FoundAd{}, nil
+	}
+	limit := offset + perPage
+	if limit > len(raw) {
+		limit = len(raw)
+	}
+	return raw[offset:limit], nil
+}
+
+func (mock *MockedDBManager) NewCategory(ctx context.Context, category models.CreatingCategory) (string, error) {
+	if err := mock.wait(ctx); err != nil {
+		return "", err
+	}
+	categoryID := uuid.New().String()
+	marshalledCategory, err := json.Marshal(models.Category{CategoryID: categoryID, ParentID: category.ParentID, Name: category.Name})
+	if err != nil {
+		return "", err
+	}
+	mock.sync.Lock()
+	mock.categories[categoryID] = marshalledCategory
+	mock.sync.Unlock()
+	return categoryID, nil
+}
+
+func (mock *MockedDBManager) SelectCategory(ctx context.Context, categoryID string) (*models.Category, error) {
+	if err := mock.wait(ctx); err != nil {
+		return nil, err
+	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	if val, ok := mock.categories[categoryID]; !ok {
+		return nil, nil
+	} else {
+		var category models.Category
+		err := json.Unmarshal(val, &category)
 		if err != nil {
 			return nil, err
 		}
-		rawCreatedAt, err := strconv.ParseInt(rawData["created_at"], 10, 64)
+		return &category, nil
+	}
+}
+
+func (mock *MockedDBManager) GetAllCategories(ctx context.Context) ([]*models.Category, error) {
+	if err := mock.wait(ctx); err != nil {
+		return nil, err
+	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	return mock.allCategories()
+}
+
+func (mock *MockedDBManager) allCategories() ([]*models.Category, error) {
+	result := make([]*models.Category, 0, len(mock.categories))
+	for _, val := range mock.categories {
+		var category models.Category
+		err := json.Unmarshal(val, &category)
 		if err != nil {
 			return nil, err
 		}
-		data.CreatedAt = time.Unix(rawCreatedAt/1000000000, rawCreatedAt%1000000000)
-		err = json.Unmarshal([]byte(rawData["photo_links"]), &data.PhotoLinks)
+		result = append(result, &category)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name == result[j].Name {
+			return result[i].CategoryID < result[j].CategoryID
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func (mock *MockedDBManager) categoryTree(rootID string) (map[string]bool, error) {
+	if rootID == "" {
+		return nil, nil
+	}
+	categories, err := mock.allCategories()
+	if err != nil {
+		return nil, err
+	}
+	return categorySubtree(rootID, categories), nil
+}
+
+// categorySubtree returns ids of rootID and all of its descendants, or an empty set when rootID is unknown.
+func categorySubtree(rootID string, categories []*models.Category) map[string]bool {
+	tree := map[string]bool{}
+	for _, category := range categories {
+		if category.CategoryID == rootID {
+			tree[rootID] = true
+		}
+	}
+	for grown := true; grown; {
+		grown = false
+		for _, category := range categories {
+			if category.ParentID != nil && tree[*category.ParentID] && !tree[category.CategoryID] {
+				tree[category.CategoryID] = true
+				grown = true
+			}
+		}
+	}
+	return tree
+}
+
+func (mock *MockedDBManager) UpdateCategory(ctx context.Context, categoryID string, category models.CreatingCategory) (*models.Category, error) {
+	if err := mock.wait(ctx); err != nil {
+		return nil, err
+	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	if _, ok := mock.categories[categoryID]; !ok {
+		return nil, nil
+	}
+	updated := models.Category{CategoryID: categoryID, ParentID: category.ParentID, Name: category.Name}
+	marshalledCategory, err := json.Marshal(updated)
+	if err != nil {
+		return nil, err
+	}
+	mock.categories[categoryID] = marshalledCategory
+	return &updated, nil
+}
+
+func (mock *MockedDBManager) DeleteCategory(ctx context.Context, categoryID string) (bool, error) {
+	if err := mock.wait(ctx); err != nil {
+		return false, err
+	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	if _, ok := mock.categories[categoryID]; !ok {
+		return false, nil
+	}
+	for _, val := range mock.categories {
+		var category models.Category
+		err := json.Unmarshal(val, &category)
 		if err != nil {
-			return nil, err
+			return false, err
+		}
+		if category.ParentID != nil && *category.ParentID == categoryID {
+			return false, ErrCategoryNotEmpty
 		}
-		raw[i] = data
-		i++
 	}
-	if sortBy == "price" {
-		if sortOrder == "asc" {
-			sort.Slice(raw, func(i, j int) bool {
-				return raw[i].Price < raw[j].Price
-			})
-		} else if sortOrder == "desc" {
-			sort.Slice(raw, func(i, j int) bool {
-				return raw[i].Price > raw[j].Price
-			})
-		}
-	} else if sortBy == "created_at" {
-		if sortOrder == "asc" {
-			sort.Slice(raw, func(i, j int) bool {
-				return raw[i].CreatedAt.UnixNano() < raw[j].CreatedAt.UnixNano()
-			})
-		} else if sortOrder == "desc" {
-			sort.Slice(raw, func(i, j int) bool {
-				return raw[i].CreatedAt.UnixNano() > raw[j].CreatedAt.UnixNano()
-			})
+	for _, val := range mock.data {
+		data, err := unmarshalAd(val)
+		if err != nil {
+			return false, err
+		}
+		if data.CategoryID == categoryID {
+			return false, ErrCategoryNotEmpty
 		}
 	}
-	if limit > perPage {
-		limit = perPage
+	delete(mock.categories, categoryID)
+	return true, nil
+}
+
+func (mock *MockedDBManager) NewUser(ctx context.Context, user models.CreatingUser) (string, error) {
+	if err := mock.wait(ctx); err != nil {
+		return "", err
+	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	for _, val := range mock.users {
+		var existing models.User
+		err := json.Unmarshal(val, &existing)
+		if err != nil {
+			return "", err
+		}
+		if strings.EqualFold(existing.Email, user.Email) {
+			return "", ErrEmailTaken
+		}
+	}
+	userID := uuid.New().String()
+	marshalledUser, err := json.Marshal(models.User{UserID: userID, Name: user.Name, Email: user.Email, CreatedAt: currentTime()})
+	if err != nil {
+		return "", err
+	}
+	mock.users[userID] = marshalledUser
+	return userID, nil
+}
+
+func (mock *MockedDBManager) SelectUser(ctx context.Context, userID string) (*models.User, error) {
+	if err := mock.wait(ctx); err != nil {
+		return nil, err
+	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	if val, ok := mock.users[userID]; !ok {
+		return nil, nil
+	} else {
+		var user models.User
+		err := json.Unmarshal(val, &user)
+		if err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+}
+
+func (mock *MockedDBManager) selectIdempotencyKey(id mockIdempotencyID) (*models.IdempotencyRecord, error) {
+	val, ok := mock.idempotencyKeys[id]
+	if !ok {
+		return nil, nil
+	}
+	var record models.IdempotencyRecord
+	err := json.Unmarshal(val, &record)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (mock *MockedDBManager) ReserveIdempotencyKey(ctx context.Context, ownerID string, key string, requestHash string, expiresAt time.Time) (*models.IdempotencyRecord, error) {
+	if err := mock.wait(ctx); err != nil {
+		return nil, err
+	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	id := mockIdempotencyID{ownerID: ownerID, key: key}
+	existing, err := mock.selectIdempotencyKey(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.ExpiresAt.After(time.Now()) {
+		return existing, nil
+	}
+	mock.idempotencyKeys[id], err = json.Marshal(models.IdempotencyRecord{OwnerID: ownerID, Key: key, RequestHash: requestHash, ExpiresAt: expiresAt.UTC().Truncate(time.Microsecond)})
+	return nil, err
+}
+
+func (mock *MockedDBManager) CompleteIdempotencyKey(ctx context.Context, ownerID string, key string, responseCode int, responseBody []byte) error {
+	if err := mock.wait(ctx); err != nil {
+		return err
+	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	id := mockIdempotencyID{ownerID: ownerID, key: key}
+	record, err := mock.selectIdempotencyKey(id)
+	if err != nil || record == nil {
+		return err
+	}
+	record.ResponseCode = responseCode
+	record.ResponseBody = responseBody
+	mock.idempotencyKeys[id], err = json.Marshal(record)
+	return err
+}
+
+func (mock *MockedDBManager) ReleaseIdempotencyKey(ctx context.Context, ownerID string, key string) error {
+	if err := mock.wait(ctx); err != nil {
+		return err
+	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	id := mockIdempotencyID{ownerID: ownerID, key: key}
+	record, err := mock.selectIdempotencyKey(id)
+	if err != nil {
+		return err
+	}
+	if record != nil && record.ResponseCode == 0 {
+		delete(mock.idempotencyKeys, id)
+	}
+	return nil
+}
+
+func (mock *MockedDBManager) PurgeIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	if err := mock.wait(ctx); err != nil {
+		return 0, err
+	}
+	mock.sync.Lock()
+	defer mock.sync.Unlock()
+	var purged int64
+	for id := range mock.idempotencyKeys {
+		record, err := mock.selectIdempotencyKey(id)
+		if err != nil {
+			return purged, err
+		}
+		if record.ExpiresAt.Before(expiredBefore) {
+			delete(mock.idempotencyKeys, id)
+			purged++
+		}
 	}
-	return raw[offset : (page-1)*perPage+limit], nil
+	return purged, nil
 }
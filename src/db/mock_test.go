@@ -13,15 +13,20 @@ import (
 )
 
 func TestMockedDBManager_GetAllAds(t *testing.T) {
+	minPrice, maxPrice := int64(50), int64(110)
+	createdAfter, createdBefore := time.Unix(1257892500, 0), time.Unix(1257893500, 0)
 	type args struct {
 		sortBy    string
 		sortOrder string
 		page      int
 		perPage   int
+		filter    models.AdsFilter
+		after     *models.AdsCursor
 	}
 	tests := []struct {
 		name           string
 		data           map[string][]byte
+		categories     map[string][]byte
 		args           args
 		expectedOutput []*models.DbAd
 		wantErr        bool
@@ -40,9 +45,9 @@ func TestMockedDBManager_GetAllAds(t *testing.T) {
 				perPage:   10,
 			},
 			expectedOutput: []*models.DbAd{
-				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000)},
-				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000)},
-				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 15, PhotoLinks: []string{"https://google.com", "https://ya.ru", "https://example.com"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000)},
+				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000).UTC()},
+				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000).UTC()},
+				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 15, PhotoLinks: []string{"https://google.com", "https://ya.ru", "https://example.com"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000).UTC()},
 			},
 			wantErr: false,
 		},
@@ -60,9 +65,9 @@ func TestMockedDBManager_GetAllAds(t *testing.T) {
 				perPage:   10,
 			},
 			expectedOutput: []*models.DbAd{
-				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 15, PhotoLinks: []string{"https://google.com", "https://ya.ru", "https://example.com"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000)},
-				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000)},
-				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000)},
+				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 15, PhotoLinks: []string{"https://google.com", "https://ya.ru", "https://example.com"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000).UTC()},
+				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000).UTC()},
+				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000).UTC()},
 			},
 			wantErr: false,
 		},
@@ -80,7 +85,7 @@ func TestMockedDBManager_GetAllAds(t *testing.T) {
 				perPage:   1,
 			},
 			expectedOutput: []*models.DbAd{
-				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000)},
+				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000).UTC()},
 			},
 			wantErr: false,
 		},
@@ -98,7 +103,7 @@ func TestMockedDBManager_GetAllAds(t *testing.T) {
 				perPage:   1,
 			},
 			expectedOutput: []*models.DbAd{
-				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 15, PhotoLinks: []string{"https://google.com", "https://ya.ru", "https://example.com"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000)},
+				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 15, PhotoLinks: []string{"https://google.com", "https://ya.ru", "https://example.com"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000).UTC()},
 			},
 			wantErr: false,
 		},
@@ -116,7 +121,7 @@ func TestMockedDBManager_GetAllAds(t *testing.T) {
 				perPage:   1,
 			},
 			expectedOutput: []*models.DbAd{
-				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 15, PhotoLinks: []string{"https://google.com", "https://ya.ru", "https://example.com"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000)},
+				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 15, PhotoLinks: []string{"https://google.com", "https://ya.ru", "https://example.com"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000).UTC()},
 			},
 			wantErr: false,
 		},
@@ -134,7 +139,7 @@ func TestMockedDBManager_GetAllAds(t *testing.T) {
 				perPage:   1,
 			},
 			expectedOutput: []*models.DbAd{
-				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000)},
+				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000).UTC()},
 			},
 			wantErr: false,
 		},
@@ -152,9 +157,9 @@ func TestMockedDBManager_GetAllAds(t *testing.T) {
 				perPage:   10,
 			},
 			expectedOutput: []*models.DbAd{
-				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000)},
-				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 15, PhotoLinks: []string{"https://google.com", "https://ya.ru", "https://example.com"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000)},
-				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000)},
+				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000).UTC()},
+				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 15, PhotoLinks: []string{"https://google.com", "https://ya.ru", "https://example.com"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000).UTC()},
+				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000).UTC()},
 			},
 			wantErr: false,
 		},
@@ -172,9 +177,9 @@ func TestMockedDBManager_GetAllAds(t *testing.T) {
 				perPage:   10,
 			},
 			expectedOutput: []*models.DbAd{
-				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000)},
-				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 15, PhotoLinks: []string{"https://google.com", "https://ya.ru", "https://example.com"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000)},
-				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000)},
+				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000).UTC()},
+				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 15, PhotoLinks: []string{"https://google.com", "https://ya.ru", "https://example.com"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000).UTC()},
+				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000).UTC()},
 			},
 			wantErr: false,
 		},
@@ -192,7 +197,7 @@ func TestMockedDBManager_GetAllAds(t *testing.T) {
 				perPage:   1,
 			},
 			expectedOutput: []*models.DbAd{
-				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000)},
+				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000).UTC()},
 			},
 			wantErr: false,
 		},
@@ -210,7 +215,7 @@ func TestMockedDBManager_GetAllAds(t *testing.T) {
 				perPage:   1,
 			},
 			expectedOutput: []*models.DbAd{
-				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000)},
+				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000).UTC()},
 			},
 			wantErr: false,
 		},
@@ -228,7 +233,7 @@ func TestMockedDBManager_GetAllAds(t *testing.T) {
 				perPage:   1,
 			},
 			expectedOutput: []*models.DbAd{
-				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000)},
+				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000).UTC()},
 			},
 			wantErr: false,
 		},
@@ -246,7 +251,247 @@ func TestMockedDBManager_GetAllAds(t *testing.T) {
 				perPage:   1,
 			},
 			expectedOutput: []*models.DbAd{
-				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000)},
+				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000).UTC()},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Price range filter",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\",\"https://google.com\",\"https://example.com\"]","price":"100","created_at":"1257892000000000000"}`),
+				"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1": []byte(`{"ad_id":"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1","title":"title 2","description":"description 2","photo_links":"[\"https://google.com\",\"https://ya.ru\",\"https://example.com\"]","price":"15","created_at":"1257893000000000000"}`),
+				"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"title 3","description":"description 3","photo_links":"[\"https://example.com\"]","price":"120","created_at":"1257894000000000000"}`),
+			},
+			args: args{
+				sortBy:    "price",
+				sortOrder: "asc",
+				page:      1,
+				perPage:   10,
+				filter:    models.AdsFilter{MinPrice: &minPrice, MaxPrice: &maxPrice},
+			},
+			expectedOutput: []*models.DbAd{
+				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000).UTC()},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Creation date range filter",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\",\"https://google.com\",\"https://example.com\"]","price":"100","created_at":"1257892000000000000"}`),
+				"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1": []byte(`{"ad_id":"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1","title":"title 2","description":"description 2","photo_links":"[\"https://google.com\",\"https://ya.ru\",\"https://example.com\"]","price":"15","created_at":"1257893000000000000"}`),
+				"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"title 3","description":"description 3","photo_links":"[\"https://example.com\"]","price":"120","created_at":"1257894000000000000"}`),
+			},
+			args: args{
+				sortBy:    "created_at",
+				sortOrder: "asc",
+				page:      1,
+				perPage:   10,
+				filter:    models.AdsFilter{CreatedAfter: &createdAfter, CreatedBefore: &createdBefore},
+			},
+			expectedOutput: []*models.DbAd{
+				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 15, PhotoLinks: []string{"https://google.com", "https://ya.ru", "https://example.com"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000).UTC()},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Keyword filter",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\",\"https://google.com\",\"https://example.com\"]","price":"100","created_at":"1257892000000000000"}`),
+				"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1": []byte(`{"ad_id":"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1","title":"title 2","description":"description 2","photo_links":"[\"https://google.com\",\"https://ya.ru\",\"https://example.com\"]","price":"15","created_at":"1257893000000000000"}`),
+				"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"title 3","description":"description 3","photo_links":"[\"https://example.com\"]","price":"120","created_at":"1257894000000000000"}`),
+			},
+			args: args{
+				sortBy:    "created_at",
+				sortOrder: "desc",
+				page:      1,
+				perPage:   10,
+				filter:    models.AdsFilter{Query: "TITLE 3"},
+			},
+			expectedOutput: []*models.DbAd{
+				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 120, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000).UTC()},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Keyword filter without matches",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\",\"https://google.com\",\"https://example.com\"]","price":"100","created_at":"1257892000000000000"}`),
+				"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1": []byte(`{"ad_id":"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1","title":"title 2","description":"description 2","photo_links":"[\"https://google.com\",\"https://ya.ru\",\"https://example.com\"]","price":"15","created_at":"1257893000000000000"}`),
+				"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"title 3","description":"description 3","photo_links":"[\"https://example.com\"]","price":"120","created_at":"1257894000000000000"}`),
+			},
+			args: args{
+				sortBy:    "created_at",
+				sortOrder: "desc",
+				page:      1,
+				perPage:   10,
+				filter:    models.AdsFilter{Query: "nothing"},
+			},
+			expectedOutput: []*models.DbAd{},
+			wantErr:        false,
+		},
+		{
+			name: "Equal prices ascending are ordered by id",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257892000000000000"}`),
+				"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1": []byte(`{"ad_id":"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1","title":"title 2","description":"description 2","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257893000000000000"}`),
+				"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"title 3","description":"description 3","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257894000000000000"}`),
+			},
+			args: args{
+				sortBy:    "price",
+				sortOrder: "asc",
+				page:      1,
+				perPage:   3,
+				after:     nil,
+			},
+			expectedOutput: []*models.DbAd{
+				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 100, PhotoLinks: []string{"https://ya.ru"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000).UTC()},
+				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 100, PhotoLinks: []string{"https://ya.ru"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000).UTC()},
+				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000).UTC()},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Equal prices descending are ordered by id",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257892000000000000"}`),
+				"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1": []byte(`{"ad_id":"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1","title":"title 2","description":"description 2","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257893000000000000"}`),
+				"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"title 3","description":"description 3","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257894000000000000"}`),
+			},
+			args: args{
+				sortBy:    "price",
+				sortOrder: "desc",
+				page:      1,
+				perPage:   3,
+				after:     nil,
+			},
+			expectedOutput: []*models.DbAd{
+				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000).UTC()},
+				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 100, PhotoLinks: []string{"https://ya.ru"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000).UTC()},
+				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 100, PhotoLinks: []string{"https://ya.ru"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000).UTC()},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Equal prices ascending after cursor",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257892000000000000"}`),
+				"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1": []byte(`{"ad_id":"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1","title":"title 2","description":"description 2","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257893000000000000"}`),
+				"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"title 3","description":"description 3","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257894000000000000"}`),
+			},
+			args: args{
+				sortBy:    "price",
+				sortOrder: "asc",
+				page:      1,
+				perPage:   1,
+				after:     &models.AdsCursor{SortBy: "price", SortOrder: "asc", Price: 100, AdID: "024e410d-f65d-470c-9920-7ddc69447ca5"},
+			},
+			expectedOutput: []*models.DbAd{
+				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 100, PhotoLinks: []string{"https://ya.ru"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000).UTC()},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Equal prices descending after cursor ignores page",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257892000000000000"}`),
+				"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1": []byte(`{"ad_id":"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1","title":"title 2","description":"description 2","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257893000000000000"}`),
+				"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"title 3","description":"description 3","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257894000000000000"}`),
+			},
+			args: args{
+				sortBy:    "price",
+				sortOrder: "desc",
+				page:      5,
+				perPage:   10,
+				after:     &models.AdsCursor{SortBy: "price", SortOrder: "desc", Price: 100, AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1"},
+			},
+			expectedOutput: []*models.DbAd{
+				{AdID: "024e410d-f65d-470c-9920-7ddc69447ca5", Title: "title 3", Description: "description 3", Price: 100, PhotoLinks: []string{"https://ya.ru"}, CreatedAt: time.Unix(1257894000000000000/1000000000, 1257894000000000000%1000000000).UTC()},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Creation date descending after cursor",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257892000000000000"}`),
+				"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1": []byte(`{"ad_id":"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1","title":"title 2","description":"description 2","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257893000000000000"}`),
+				"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"title 3","description":"description 3","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257894000000000000"}`),
+			},
+			args: args{
+				sortBy:    "created_at",
+				sortOrder: "desc",
+				page:      1,
+				perPage:   10,
+				after:     &models.AdsCursor{SortBy: "created_at", SortOrder: "desc", CreatedAt: time.Unix(1257894000, 0).UTC(), AdID: "024e410d-f65d-470c-9920-7ddc69447ca5"},
+			},
+			expectedOutput: []*models.DbAd{
+				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 100, PhotoLinks: []string{"https://ya.ru"}, CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000).UTC()},
+				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000).UTC()},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Creation date ascending after last ad",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257892000000000000"}`),
+				"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1": []byte(`{"ad_id":"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1","title":"title 2","description":"description 2","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257893000000000000"}`),
+				"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"title 3","description":"description 3","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257894000000000000"}`),
+			},
+			args: args{
+				sortBy:    "created_at",
+				sortOrder: "asc",
+				page:      1,
+				perPage:   10,
+				after:     &models.AdsCursor{SortBy: "created_at", SortOrder: "asc", CreatedAt: time.Unix(1257894000, 0).UTC(), AdID: "024e410d-f65d-470c-9920-7ddc69447ca5"},
+			},
+			expectedOutput: []*models.DbAd{},
+			wantErr:        false,
+		},
+		{
+			name: "Category filter includes descendants",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","category_id":"9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d","created_at":"1257892000000000000"}`),
+				"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1": []byte(`{"ad_id":"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1","title":"title 2","description":"description 2","photo_links":"[\"https://ya.ru\"]","price":"15","category_id":"1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed","created_at":"1257893000000000000"}`),
+				"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"title 3","description":"description 3","photo_links":"[\"https://ya.ru\"]","price":"120","category_id":"6ec0bd7f-11c0-43da-975e-2a8ad9ebae0b","created_at":"1257894000000000000"}`),
+			},
+			categories: map[string][]byte{
+				"9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d": []byte(`{"categoryID":"9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d","name":"parent"}`),
+				"1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed": []byte(`{"categoryID":"1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed","parentID":"9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d","name":"child"}`),
+				"6ec0bd7f-11c0-43da-975e-2a8ad9ebae0b": []byte(`{"categoryID":"6ec0bd7f-11c0-43da-975e-2a8ad9ebae0b","name":"other"}`),
+			},
+			args: args{
+				sortBy:    "created_at",
+				sortOrder: "desc",
+				page:      1,
+				perPage:   10,
+				filter:    models.AdsFilter{CategoryID: "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d"},
+			},
+			expectedOutput: []*models.DbAd{
+				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 15, PhotoLinks: []string{"https://ya.ru"}, CategoryID: "1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed", CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000).UTC()},
+				{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru"}, CategoryID: "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d", CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000).UTC()},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Category filter of leaf",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","category_id":"9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d","created_at":"1257892000000000000"}`),
+				"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1": []byte(`{"ad_id":"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1","title":"title 2","description":"description 2","photo_links":"[\"https://ya.ru\"]","price":"15","category_id":"1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed","created_at":"1257893000000000000"}`),
+				"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"title 3","description":"description 3","photo_links":"[\"https://ya.ru\"]","price":"120","category_id":"6ec0bd7f-11c0-43da-975e-2a8ad9ebae0b","created_at":"1257894000000000000"}`),
+			},
+			categories: map[string][]byte{
+				"9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d": []byte(`{"categoryID":"9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d","name":"parent"}`),
+				"1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed": []byte(`{"categoryID":"1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed","parentID":"9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d","name":"child"}`),
+				"6ec0bd7f-11c0-43da-975e-2a8ad9ebae0b": []byte(`{"categoryID":"6ec0bd7f-11c0-43da-975e-2a8ad9ebae0b","name":"other"}`),
+			},
+			args: args{
+				sortBy:    "created_at",
+				sortOrder: "desc",
+				page:      1,
+				perPage:   10,
+				filter:    models.AdsFilter{CategoryID: "1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed"},
+			},
+			expectedOutput: []*models.DbAd{
+				{AdID: "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1", Title: "title 2", Description: "description 2", Price: 15, PhotoLinks: []string{"https://ya.ru"}, CategoryID: "1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed", CreatedAt: time.Unix(1257893000000000000/1000000000, 1257893000000000000%1000000000).UTC()},
 			},
 			wantErr: false,
 		},
@@ -254,11 +499,11 @@ func TestMockedDBManager_GetAllAds(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			db := MockedDBManager{
-				data: tt.data,
-				ctx:  context.Background(),
-				sync: sync.Mutex{},
+				data:       tt.data,
+				categories: tt.categories,
+				sync:       sync.Mutex{},
 			}
-			got, err := db.GetAllAds(tt.args.sortBy, tt.args.sortOrder, tt.args.page, tt.args.perPage)
+			got, err := db.GetAllAds(context.Background(), tt.args.sortBy, tt.args.sortOrder, tt.args.page, tt.args.perPage, tt.args.filter, tt.args.after)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetAllAds() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -272,7 +517,8 @@ func TestMockedDBManager_GetAllAds(t *testing.T) {
 
 func TestMockedDBManager_NewAd(t *testing.T) {
 	type args struct {
-		adData models.CreatingAd
+		adData  models.CreatingAd
+		ownerID string
 	}
 	tests := []struct {
 		name    string
@@ -284,13 +530,13 @@ func TestMockedDBManager_NewAd(t *testing.T) {
 		{
 			name:    "Creating new ad",
 			db:      NewMockedDBManager(),
-			args:    args{models.CreatingAd{Title: "title 1", Price: 100, Description: "description 1", PhotoLinks: []string{"https://ya.ru"}}},
+			args:    args{models.CreatingAd{Title: "title 1", Price: 100, Description: "description 1", PhotoLinks: []string{"https://ya.ru"}}, "owner"},
 			wantErr: false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := tt.db.NewAd(tt.args.adData)
+			got, err := tt.db.NewAd(context.Background(), tt.args.adData, tt.args.ownerID)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewAd() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -319,7 +565,7 @@ func TestMockedDBManager_SelectAd(t *testing.T) {
 				"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"title 3","description":"description 3","photo_links":"[\"https://example.com\"]","price":"120","created_at":"1257894000000000000"}`),
 			},
 			args:           args{adID: "22e88a53-3c80-429d-9e84-99d217788098"},
-			expectedOutput: &models.DbAd{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000)},
+			expectedOutput: &models.DbAd{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000).UTC()},
 			wantErr:        false,
 		},
 		{
@@ -338,10 +584,9 @@ func TestMockedDBManager_SelectAd(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			db := MockedDBManager{
 				data: tt.data,
-				ctx:  context.Background(),
 				sync: sync.Mutex{},
 			}
-			got, err := db.SelectAd(tt.args.adID)
+			got, err := db.SelectAd(context.Background(), tt.args.adID)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("SelectAd() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -352,3 +597,580 @@ func TestMockedDBManager_SelectAd(t *testing.T) {
 		})
 	}
 }
+
+func TestMockedDBManager_UpdateAd(t *testing.T) {
+	title := "new title"
+	price := int64(250)
+	photoLinks := []string{"https://example.com"}
+	type args struct {
+		adID   string
+		adData models.UpdatingAd
+	}
+	tests := []struct {
+		name           string
+		data           map[string][]byte
+		args           args
+		expectedOutput *models.DbAd
+		wantErr        bool
+	}{
+		{
+			name: "Update title",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\",\"https://google.com\",\"https://example.com\"]","price":"100","created_at":"1257892000000000000"}`),
+			},
+			args:           args{adID: "22e88a53-3c80-429d-9e84-99d217788098", adData: models.UpdatingAd{Title: &title}},
+			expectedOutput: &models.DbAd{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "new title", Description: "description 1", Price: 100, PhotoLinks: []string{"https://ya.ru", "https://google.com", "https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000).UTC()},
+			wantErr:        false,
+		},
+		{
+			name: "Update price and photo links",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\",\"https://google.com\",\"https://example.com\"]","price":"100","created_at":"1257892000000000000"}`),
+			},
+			args:           args{adID: "22e88a53-3c80-429d-9e84-99d217788098", adData: models.UpdatingAd{Price: &price, PhotoLinks: &photoLinks}},
+			expectedOutput: &models.DbAd{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Title: "title 1", Description: "description 1", Price: 250, PhotoLinks: []string{"https://example.com"}, CreatedAt: time.Unix(1257892000000000000/1000000000, 1257892000000000000%1000000000).UTC()},
+			wantErr:        false,
+		},
+		{
+			name: "Update not existing ad",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\",\"https://google.com\",\"https://example.com\"]","price":"100","created_at":"1257892000000000000"}`),
+			},
+			args:           args{adID: "abcd", adData: models.UpdatingAd{Title: &title}},
+			expectedOutput: nil,
+			wantErr:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := MockedDBManager{
+				data: tt.data,
+				sync: sync.Mutex{},
+			}
+			got, err := db.UpdateAd(context.Background(), tt.args.adID, tt.args.adData)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UpdateAd() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != nil {
+				assert.True(t, got.UpdatedAt.After(got.CreatedAt), "updated_at wasn't bumped")
+				got.UpdatedAt = time.Time{}
+			}
+			if !reflect.DeepEqual(got, tt.expectedOutput) {
+				t.Errorf("UpdateAd() got = %v, expectedOutput %v", got, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestMockedDBManager_DeleteAd(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           map[string][]byte
+		adID           string
+		expectedOutput bool
+		wantErr        bool
+	}{
+		{
+			name: "Delete ad",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257892000000000000"}`),
+			},
+			adID:           "22e88a53-3c80-429d-9e84-99d217788098",
+			expectedOutput: true,
+		},
+		{
+			name: "Delete already deleted ad",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257892000000000000","deleted_at":"1257893000000000000"}`),
+			},
+			adID:           "22e88a53-3c80-429d-9e84-99d217788098",
+			expectedOutput: false,
+		},
+		{
+			name: "Delete not existing ad",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257892000000000000"}`),
+			},
+			adID:           "abcd",
+			expectedOutput: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := MockedDBManager{
+				data: tt.data,
+				sync: sync.Mutex{},
+			}
+			got, err := db.DeleteAd(context.Background(), tt.adID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DeleteAd() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.expectedOutput, got)
+			selected, err := db.SelectAd(context.Background(), tt.adID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Nil(t, selected, "deleted ad is still selectable")
+		})
+	}
+}
+
+func TestMockedDBManager_RestoreAd(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           map[string][]byte
+		adID           string
+		expectedOutput bool
+		wantErr        bool
+	}{
+		{
+			name: "Restore deleted ad",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257892000000000000","deleted_at":"1257893000000000000"}`),
+			},
+			adID:           "22e88a53-3c80-429d-9e84-99d217788098",
+			expectedOutput: true,
+		},
+		{
+			name: "Restore not deleted ad",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257892000000000000"}`),
+			},
+			adID:           "22e88a53-3c80-429d-9e84-99d217788098",
+			expectedOutput: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := MockedDBManager{
+				data: tt.data,
+				sync: sync.Mutex{},
+			}
+			got, err := db.RestoreAd(context.Background(), tt.adID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RestoreAd() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.expectedOutput, got)
+			selected, err := db.SelectAd(context.Background(), tt.adID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.NotNil(t, selected, "restored ad isn't selectable")
+		})
+	}
+}
+
+func TestMockedDBManager_PurgeDeletedAds(t *testing.T) {
+	db := MockedDBManager{
+		data: map[string][]byte{
+			"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257892000000000000","deleted_at":"1257893000000000000"}`),
+			"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1": []byte(`{"ad_id":"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1","title":"title 2","description":"description 2","photo_links":"[\"https://ya.ru\"]","price":"15","created_at":"1257892000000000000","deleted_at":"1257899000000000000"}`),
+			"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"title 3","description":"description 3","photo_links":"[\"https://ya.ru\"]","price":"120","created_at":"1257892000000000000"}`),
+		},
+		sync: sync.Mutex{},
+	}
+	purged, err := db.PurgeDeletedAds(context.Background(), time.Unix(1257895000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), purged)
+	_, ok := db.data["22e88a53-3c80-429d-9e84-99d217788098"]
+	assert.False(t, ok, "ad deleted before retention wasn't purged")
+	assert.Equal(t, 2, len(db.data))
+}
+
+func TestMockedDBManager_CountAds(t *testing.T) {
+	minPrice := int64(50)
+	db := MockedDBManager{
+		data: map[string][]byte{
+			"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257892000000000000"}`),
+			"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1": []byte(`{"ad_id":"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1","title":"title 2","description":"description 2","photo_links":"[\"https://ya.ru\"]","price":"15","created_at":"1257893000000000000"}`),
+			"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"title 3","description":"description 3","photo_links":"[\"https://ya.ru\"]","price":"120","created_at":"1257894000000000000","deleted_at":"1257895000000000000"}`),
+		},
+		sync: sync.Mutex{},
+	}
+	tests := []struct {
+		name           string
+		filter         models.AdsFilter
+		expectedOutput int64
+	}{
+		{name: "Count all but deleted", filter: models.AdsFilter{}, expectedOutput: 2},
+		{name: "Count by price", filter: models.AdsFilter{MinPrice: &minPrice}, expectedOutput: 1},
+		{name: "Count by keyword", filter: models.AdsFilter{Query: "title"}, expectedOutput: 2},
+		{name: "Count without matches", filter: models.AdsFilter{Query: "nothing"}, expectedOutput: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := db.CountAds(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.expectedOutput, got)
+		})
+	}
+}
+
+func TestMockedDBManager_SearchAds(t *testing.T) {
+	db := MockedDBManager{
+		data: map[string][]byte{
+			"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"Red bicycle","description":"Almost new","photo_links":"[\"https://ya.ru\"]","price":"100","created_at":"1257892000000000000"}`),
+			"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1": []byte(`{"ad_id":"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1","title":"Garage sale","description":"Chairs and a red bicycle","photo_links":"[\"https://ya.ru\"]","price":"15","created_at":"1257893000000000000"}`),
+			"024e410d-f65d-470c-9920-7ddc69447ca5": []byte(`{"ad_id":"024e410d-f65d-470c-9920-7ddc69447ca5","title":"Red bicycle","description":"Sold","photo_links":"[\"https://ya.ru\"]","price":"120","created_at":"1257894000000000000","deleted_at":"1257895000000000000"}`),
+		},
+		sync: sync.Mutex{},
+	}
+	tests := []struct {
+		name             string
+		query            string
+		page             int
+		perPage          int
+		withSnippets     bool
+		expectedIDs      []string
+		expectedSnippets []string
+	}{
+		{
+			name:        "Rank by title matches",
+			query:       "bicycle",
+			page:        1,
+			perPage:     10,
+			expectedIDs: []string{"22e88a53-3c80-429d-9e84-99d217788098", "155d4a0d-52a7-42b0-a2a4-f58f4c953dc1"},
+		},
+		{
+			name:        "Second page",
+			query:       "bicycle",
+			page:        2,
+			perPage:     1,
+			expectedIDs: []string{"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1"},
+		},
+		{
+			name:             "Snippets",
+			query:            "CHAIRS",
+			page:             1,
+			perPage:          10,
+			withSnippets:     true,
+			expectedIDs:      []string{"155d4a0d-52a7-42b0-a2a4-f58f4c953dc1"},
+			expectedSnippets: []string{"Garage sale <b>Chairs</b> and a red bicycle"},
+		},
+		{
+			name:        "Only punctuation",
+			query:       "?!",
+			page:        1,
+			perPage:     10,
+			expectedIDs: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := db.SearchAds(context.Background(), tt.query, tt.page, tt.perPage, tt.withSnippets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotIDs := []string{}
+			gotSnippets := []string{}
+			for _, foundAd := range got {
+				gotIDs = append(gotIDs, foundAd.AdID)
+				if foundAd.Snippet != "" {
+					gotSnippets = append(gotSnippets, foundAd.Snippet)
+				}
+			}
+			assert.Equal(t, tt.expectedIDs, gotIDs)
+			if tt.withSnippets {
+				assert.Equal(t, tt.expectedSnippets, gotSnippets)
+			}
+		})
+	}
+}
+
+func TestMockedDBManager_DeleteCategory(t *testing.T) {
+	parentID := "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d"
+	tests := []struct {
+		name           string
+		data           map[string][]byte
+		categories     map[string][]byte
+		categoryID     string
+		expectedOutput bool
+		expectedErr    error
+	}{
+		{
+			name: "Delete empty category",
+			data: map[string][]byte{},
+			categories: map[string][]byte{
+				parentID: []byte(`{"categoryID":"9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d","name":"parent"}`),
+			},
+			categoryID:     parentID,
+			expectedOutput: true,
+		},
+		{
+			name: "Delete category with subcategories",
+			data: map[string][]byte{},
+			categories: map[string][]byte{
+				parentID:                               []byte(`{"categoryID":"9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d","name":"parent"}`),
+				"1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed": []byte(`{"categoryID":"1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed","parentID":"9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d","name":"child"}`),
+			},
+			categoryID:  parentID,
+			expectedErr: ErrCategoryNotEmpty,
+		},
+		{
+			name: "Delete category with deleted ads",
+			data: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"ad_id":"22e88a53-3c80-429d-9e84-99d217788098","title":"title 1","description":"description 1","photo_links":"[\"https://ya.ru\"]","price":"100","category_id":"9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d","created_at":"1257892000000000000","deleted_at":"1257893000000000000"}`),
+			},
+			categories: map[string][]byte{
+				parentID: []byte(`{"categoryID":"9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d","name":"parent"}`),
+			},
+			categoryID:  parentID,
+			expectedErr: ErrCategoryNotEmpty,
+		},
+		{
+			name:           "Delete not existing category",
+			data:           map[string][]byte{},
+			categories:     map[string][]byte{},
+			categoryID:     parentID,
+			expectedOutput: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := MockedDBManager{
+				data:       tt.data,
+				categories: tt.categories,
+				sync:       sync.Mutex{},
+			}
+			got, err := db.DeleteCategory(context.Background(), tt.categoryID)
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedOutput, got)
+		})
+	}
+}
+
+func TestMockedDBManager_NewUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		users       map[string][]byte
+		user        models.CreatingUser
+		expectedErr error
+	}{
+		{
+			name:  "Create user",
+			users: map[string][]byte{},
+			user:  models.CreatingUser{Name: "seller", Email: "seller@example.com"},
+		},
+		{
+			name: "Create user with taken email",
+			users: map[string][]byte{
+				"22e88a53-3c80-429d-9e84-99d217788098": []byte(`{"userID":"22e88a53-3c80-429d-9e84-99d217788098","name":"seller","email":"seller@example.com"}`),
+			},
+			user:        models.CreatingUser{Name: "another seller", Email: "Seller@Example.com"},
+			expectedErr: ErrEmailTaken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := MockedDBManager{
+				users: tt.users,
+				sync:  sync.Mutex{},
+			}
+			got, err := db.NewUser(context.Background(), tt.user)
+			assert.Equal(t, tt.expectedErr, err)
+			if tt.expectedErr == nil {
+				user, err := db.SelectUser(context.Background(), got)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.user.Email, user.Email)
+			}
+		})
+	}
+}
+
+func TestMockedDBManager_Latency(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name        string
+		latency     time.Duration
+		timeout     time.Duration
+		ctx         context.Context
+		expectedErr error
+	}{
+		{
+			name:    "Latency within deadline",
+			latency: time.Millisecond,
+			timeout: time.Second,
+			ctx:     context.Background(),
+		},
+		{
+			name:        "Latency exceeds deadline",
+			latency:     time.Second,
+			timeout:     10 * time.Millisecond,
+			ctx:         context.Background(),
+			expectedErr: context.DeadlineExceeded,
+		},
+		{
+			name:        "Canceled context without latency",
+			ctx:         canceled,
+			expectedErr: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewMockedDBManager()
+			db.SetLatency(tt.latency)
+			ctx := tt.ctx
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			started := time.Now()
+			_, err := db.CountAds(ctx, models.AdsFilter{})
+			assert.Equal(t, tt.expectedErr, err)
+			if tt.timeout > 0 {
+				assert.Less(t, int64(time.Since(started)), int64(tt.timeout+500*time.Millisecond))
+			}
+		})
+	}
+}
+
+func TestMockedDBManager_IdempotencyKeys(t *testing.T) {
+	tests := []struct {
+		name                 string
+		existing             *models.IdempotencyRecord
+		complete             bool
+		release              bool
+		expectedRecord       bool
+		expectedResponseCode int
+	}{
+		{
+			name: "Reserve new key",
+		},
+		{
+			name:           "Reserve pending key",
+			existing:       &models.IdempotencyRecord{RequestHash: "hash", ExpiresAt: time.Now().Add(time.Hour)},
+			expectedRecord: true,
+		},
+		{
+			name:                 "Reserve completed key",
+			existing:             &models.IdempotencyRecord{RequestHash: "hash", ExpiresAt: time.Now().Add(time.Hour)},
+			complete:             true,
+			expectedRecord:       true,
+			expectedResponseCode: 200,
+		},
+		{
+			name:     "Reserve released key",
+			existing: &models.IdempotencyRecord{RequestHash: "hash", ExpiresAt: time.Now().Add(time.Hour)},
+			release:  true,
+		},
+		{
+			name:     "Reserve expired key",
+			existing: &models.IdempotencyRecord{RequestHash: "hash", ExpiresAt: time.Now().Add(-time.Hour)},
+			complete: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewMockedDBManager()
+			ctx := context.Background()
+			if tt.existing != nil {
+				record, err := db.ReserveIdempotencyKey(ctx, "owner", "key", tt.existing.RequestHash, tt.existing.ExpiresAt)
+				assert.NoError(t, err)
+				assert.Nil(t, record)
+				if tt.complete {
+					assert.NoError(t, db.CompleteIdempotencyKey(ctx, "owner", "key", 200, []byte(`{"ad_id":"1"}`)))
+				}
+				if tt.release {
+					assert.NoError(t, db.ReleaseIdempotencyKey(ctx, "owner", "key"))
+				}
+			}
+			record, err := db.ReserveIdempotencyKey(ctx, "owner", "key", "hash", time.Now().Add(time.Hour))
+			assert.NoError(t, err)
+			if !tt.expectedRecord {
+				assert.Nil(t, record)
+				return
+			}
+			assert.Equal(t, "hash", record.RequestHash)
+			assert.Equal(t, tt.expectedResponseCode, record.ResponseCode)
+			if tt.complete {
+				assert.Equal(t, []byte(`{"ad_id":"1"}`), record.ResponseBody)
+			}
+			other, err := db.ReserveIdempotencyKey(ctx, "another owner", "key", "hash", time.Now().Add(time.Hour))
+			assert.NoError(t, err)
+			assert.Nil(t, other, "keys should be scoped to owner")
+		})
+	}
+}
+
+func TestMockedDBManager_IdempotencyKeysDontCollide(t *testing.T) {
+	db := NewMockedDBManager()
+	ctx := context.Background()
+	record, err := db.ReserveIdempotencyKey(ctx, "owner/a", "b", "hash", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+	record, err = db.ReserveIdempotencyKey(ctx, "owner", "a/b", "other", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+}
+
+func TestMockedDBManager_PurgeIdempotencyKeys(t *testing.T) {
+	db := NewMockedDBManager()
+	ctx := context.Background()
+	_, err := db.ReserveIdempotencyKey(ctx, "owner", "expired", "hash", time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	_, err = db.ReserveIdempotencyKey(ctx, "owner", "alive", "hash", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	purged, err := db.PurgeIdempotencyKeys(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	record, err := db.ReserveIdempotencyKey(ctx, "owner", "alive", "hash", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.NotNil(t, record)
+}
+
+func TestMockedDBManager_NewAds(t *testing.T) {
+	db := NewMockedDBManager()
+	ads := []models.CreatingAd{
+		{Title: "title 1", Description: "description 1", PhotoLinks: []string{"https://ya.ru"}, Price: 100},
+		{Title: "title 2", Description: "description 2", PhotoLinks: []string{"https://example.com"}, Price: 200},
+	}
+	adIDs, err := db.NewAds(context.Background(), ads, "owner")
+	assert.NoError(t, err)
+	assert.Equal(t, len(ads), len(adIDs))
+	var createdAt []time.Time
+	for i, adID := range adIDs {
+		ad, err := db.SelectAd(context.Background(), adID)
+		assert.NoError(t, err)
+		assert.Equal(t, ads[i].Title, ad.Title)
+		assert.Equal(t, ads[i].PhotoLinks, ad.PhotoLinks)
+		assert.Equal(t, "owner", ad.OwnerID)
+		createdAt = append(createdAt, ad.CreatedAt)
+	}
+	assert.Equal(t, createdAt[0], createdAt[1])
+}
+
+func TestMockedDBManager_ConcurrentAccess(t *testing.T) {
+	db := NewMockedDBManager()
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_, err := db.NewAd(ctx, models.CreatingAd{Title: "title", Description: "description", PhotoLinks: []string{"https://ya.ru"}, Price: int64(i + 1)}, "owner")
+			assert.NoError(t, err)
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, err := db.GetAllAds(ctx, "price", "asc", 1, 10, models.AdsFilter{}, nil)
+			assert.NoError(t, err)
+			_, err = db.SearchAds(ctx, "title", 1, 10, false)
+			assert.NoError(t, err)
+			_, err = db.CountAds(ctx, models.AdsFilter{})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	count, err := db.CountAds(ctx, models.AdsFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), count)
+}
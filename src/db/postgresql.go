@@ -2,103 +2,453 @@ package db
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"adv-backend-trainee-assignment/src/models"
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 type PostgreSQLManager struct {
-	pool *pgxpool.Pool
-	ctx  context.Context
+	pool         *pgxpool.Pool
+	queryTimeout time.Duration
 }
 
-func NewPostgreSQLManager(dbUrl string) (*PostgreSQLManager, error) {
-	ctx := context.Background()
+func NewPostgreSQLManager(ctx context.Context, dbUrl string, queryTimeout time.Duration) (*PostgreSQLManager, error) {
 	db, err := pgxpool.Connect(ctx, dbUrl)
 	if err != nil {
 		return nil, fmt.Errorf("can't open postgresql db: %s", err)
 	} else {
-		return &PostgreSQLManager{pool: db, ctx: ctx}, nil
+		return &PostgreSQLManager{pool: db, queryTimeout: queryTimeout}, nil
 	}
 }
 
+// withQueryTimeout bounds a single query. The request deadline still applies if it comes first.
+func (postgre PostgreSQLManager) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if postgre.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, postgre.queryTimeout)
+}
+
+func (postgre PostgreSQLManager) PoolStats() PoolStats {
+	stat := postgre.pool.Stat()
+	return PoolStats{Acquired: stat.AcquiredConns(), Idle: stat.IdleConns(), Total: stat.TotalConns()}
+}
+
+func (postgre PostgreSQLManager) Ping(ctx context.Context) error {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	conn, err := postgre.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	return conn.Conn().Ping(ctx)
+}
+
 func (postgre PostgreSQLManager) Close() error {
 	postgre.pool.Close()
 	return nil
 }
 
-func (postgre PostgreSQLManager) NewAd(adData models.CreatingAd) (string, error) {
+func (postgre PostgreSQLManager) NewAd(ctx context.Context, adData models.CreatingAd, ownerID string) (string, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
 	adID := uuid.New().String()
-	marshalledPhotoLinks, err := json.Marshal(adData.PhotoLinks)
+	now := currentTime()
+	_, err := postgre.pool.Exec(ctx, "INSERT INTO ads (ad_id, title, description, price, photo_links, category_id, owner_id, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)", adID, adData.Title, adData.Description, adData.Price, adData.PhotoLinks, nullableString(adData.CategoryID), ownerID, now, now)
 	if err != nil {
 		return "", err
+	}
+	return adID, nil
+}
+
+func (postgre PostgreSQLManager) NewAds(ctx context.Context, ads []models.CreatingAd, ownerID string) ([]string, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	adIDs := make([]string, len(ads))
+	rows := make([][]interface{}, len(ads))
+	now := currentTime()
+	for i, adData := range ads {
+		adIDs[i] = uuid.New().String()
+		rows[i] = []interface{}{adIDs[i], adData.Title, adData.Description, adData.Price, adData.PhotoLinks, nullableString(adData.CategoryID), ownerID, now, now}
+	}
+	// COPY is a single statement, so either all rows are inserted or none
+	_, err := postgre.pool.CopyFrom(ctx, pgx.Identifier{"ads"}, []string{"ad_id", "title", "description", "price", "photo_links", "category_id", "owner_id", "created_at", "updated_at"}, pgx.CopyFromRows(rows))
+	if err != nil {
+		return nil, err
+	}
+	return adIDs, nil
+}
+
+const adColumns = "ad_id, title, description, price, photo_links, category_id, owner_id, created_at, updated_at, deleted_at"
+
+func scanAd(row pgx.Row, extra ...interface{}) (*models.DbAd, error) {
+	var res models.DbAd
+	var categoryID, ownerID *string
+	err := row.Scan(append([]interface{}{&res.AdID, &res.Title, &res.Description, &res.Price, &res.PhotoLinks, &categoryID, &ownerID, &res.CreatedAt, &res.UpdatedAt, &res.DeletedAt}, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	// pgx decodes timestamptz in the local time zone, other backends return UTC
+	res.CreatedAt = res.CreatedAt.UTC()
+	res.UpdatedAt = res.UpdatedAt.UTC()
+	if res.DeletedAt != nil {
+		deletedAt := res.DeletedAt.UTC()
+		res.DeletedAt = &deletedAt
+	}
+	if categoryID != nil {
+		res.CategoryID = *categoryID
+	}
+	if ownerID != nil {
+		res.OwnerID = *ownerID
+	}
+	return &res, nil
+}
+
+func (postgre PostgreSQLManager) SelectAd(ctx context.Context, adID string) (*models.DbAd, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	res, err := scanAd(postgre.pool.QueryRow(ctx, "SELECT "+adColumns+" FROM ads WHERE ad_id = $1 AND deleted_at IS NULL", adID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return res, err
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func adsFilterClause(filter models.AdsFilter) (string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.MinPrice != nil {
+		add("price >= $%d", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		add("price <= $%d", *filter.MaxPrice)
+	}
+	if filter.CreatedAfter != nil {
+		add("created_at >= $%d", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		add("created_at <= $%d", *filter.CreatedBefore)
+	}
+	if filter.Query != "" {
+		add("(title ILIKE $%[1]d OR description ILIKE $%[1]d)", "%"+likeEscaper.Replace(filter.Query)+"%")
+	}
+	if filter.OwnerID != "" {
+		add("owner_id = $%d", filter.OwnerID)
+	}
+	if filter.CategoryID != "" {
+		add("category_id IN (WITH RECURSIVE tree AS (SELECT category_id FROM categories WHERE category_id = $%d UNION ALL SELECT categories.category_id FROM categories JOIN tree ON categories.parent_id = tree.category_id) SELECT category_id FROM tree)", filter.CategoryID)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func (postgre PostgreSQLManager) GetAllAds(ctx context.Context, sortBy string, sortOrder string, page int, perPage int, filter models.AdsFilter, after *models.AdsCursor) ([]*models.DbAd, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	where, args := adsFilterClause(filter)
+	offset := (page - 1) * perPage
+	if after != nil {
+		var cursorValue interface{} = after.CreatedAt
+		if sortBy == "price" {
+			cursorValue = after.Price
+		}
+		comparison := "<"
+		if sortOrder == "asc" {
+			comparison = ">"
+		}
+		args = append(args, cursorValue, after.AdID)
+		where += fmt.Sprintf(" AND (%s, ad_id) %s ($%d, $%d)", sortBy, comparison, len(args)-1, len(args))
+		offset = 0
+	}
+	rows, err := postgre.pool.Query(ctx, fmt.Sprintf("SELECT %s FROM ads WHERE %s ORDER BY %s %s, ad_id %[4]s LIMIT %d OFFSET %d", adColumns, where, sortBy, sortOrder, perPage, offset), args...)
+	if err != nil {
+		return nil, err
 	} else {
-		_, err := postgre.pool.Exec(postgre.ctx, "INSERT INTO ads (ad_id, title, description, price, photo_links, created_at) VALUES ($1, $2, $3, $4, $5, $6)", adID, adData.Title, adData.Description, adData.Price, marshalledPhotoLinks, time.Now().UTC().Unix())
-		if err != nil {
-			return "", err
+		defer rows.Close()
+		result := []*models.DbAd{}
+		for rows.Next() {
+			res, err := scanAd(rows)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, res)
 		}
-		return adID, nil
+		return result, rows.Err()
 	}
 }
 
-func (postgre PostgreSQLManager) SelectAd(adID string) (*models.DbAd, error) {
-	rows, err := postgre.pool.Query(postgre.ctx, "SELECT * FROM ads WHERE ad_id = $1", adID)
+func (postgre PostgreSQLManager) CountAds(ctx context.Context, filter models.AdsFilter) (int64, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	where, args := adsFilterClause(filter)
+	var count int64
+	err := postgre.pool.QueryRow(ctx, "SELECT count(*) FROM ads WHERE "+where, args...).Scan(&count)
+	return count, err
+}
+
+func (postgre PostgreSQLManager) SearchAds(ctx context.Context, query string, page int, perPage int, withSnippets bool) ([]*models.FoundAd, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	snippet := "''"
+	if withSnippets {
+		snippet = "ts_headline('simple', title || ' ' || description, query, 'StartSel=<b>, StopSel=</b>, MaxFragments=2')"
+	}
+	rows, err := postgre.pool.Query(ctx, fmt.Sprintf("SELECT %s, ts_rank(search_vector, query) AS rank, %s FROM ads, websearch_to_tsquery('simple', $1) query WHERE deleted_at IS NULL AND search_vector @@ query ORDER BY rank DESC, ad_id LIMIT %d OFFSET %d", adColumns, snippet, perPage, (page-1)*perPage), query)
 	if err != nil {
 		return nil, err
 	} else {
 		defer rows.Close()
+		result := []*models.FoundAd{}
 		for rows.Next() {
-			var res models.DbAd
-			var tmp string
-			var createdAt int64
-			err := rows.Scan(&res.AdID, &res.Title, &res.Description, &res.Price, &tmp, &createdAt)
+			var rank float32
+			var snippet string
+			res, err := scanAd(rows, &rank, &snippet)
 			if err != nil {
 				return nil, err
-			} else {
-				res.CreatedAt = time.Unix(createdAt, 0)
-				err := json.Unmarshal([]byte(tmp), &res.PhotoLinks)
-				if err != nil {
-					return nil, err
-				} else {
-					return &res, nil
-				}
 			}
+			result = append(result, &models.FoundAd{DbAd: *res, Rank: float64(rank), Snippet: snippet})
 		}
+		return result, rows.Err()
+	}
+}
+
+func (postgre PostgreSQLManager) UpdateAd(ctx context.Context, adID string, adData models.UpdatingAd) (*models.DbAd, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	var columns []string
+	var args []interface{}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		columns = append(columns, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if adData.Title != nil {
+		set("title", *adData.Title)
+	}
+	if adData.Description != nil {
+		set("description", *adData.Description)
+	}
+	if adData.Price != nil {
+		set("price", *adData.Price)
 	}
-	if rows.Err() != nil {
-		return nil, rows.Err()
+	if adData.PhotoLinks != nil {
+		set("photo_links", *adData.PhotoLinks)
 	}
-	return nil, nil
+	if adData.CategoryID != nil {
+		set("category_id", nullableString(*adData.CategoryID))
+	}
+	set("updated_at", currentTime())
+	args = append(args, adID)
+	query := fmt.Sprintf("UPDATE ads SET %s WHERE ad_id = $%d AND deleted_at IS NULL RETURNING %s", strings.Join(columns, ", "), len(args), adColumns)
+	res, err := scanAd(postgre.pool.QueryRow(ctx, query, args...))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return res, err
+}
+
+func (postgre PostgreSQLManager) DeleteAd(ctx context.Context, adID string) (bool, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	tag, err := postgre.pool.Exec(ctx, "UPDATE ads SET deleted_at = $1 WHERE ad_id = $2 AND deleted_at IS NULL", currentTime(), adID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (postgre PostgreSQLManager) RestoreAd(ctx context.Context, adID string) (bool, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	tag, err := postgre.pool.Exec(ctx, "UPDATE ads SET deleted_at = NULL, updated_at = $1 WHERE ad_id = $2 AND deleted_at IS NOT NULL", currentTime(), adID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (postgre PostgreSQLManager) PurgeDeletedAds(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	tag, err := postgre.pool.Exec(ctx, "DELETE FROM ads WHERE deleted_at < $1", deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
 }
 
-func (postgre PostgreSQLManager) GetAllAds(sortBy string, sortOrder string, page int, perPage int) ([]*models.DbAd, error) {
-	rows, err := postgre.pool.Query(postgre.ctx, fmt.Sprintf("SELECT * FROM ads ORDER BY %s %s LIMIT %d OFFSET %d", sortBy, sortOrder, perPage, (page-1)*perPage))
+const categoryColumns = "category_id, parent_id, name"
+
+func scanCategory(row pgx.Row) (*models.Category, error) {
+	var res models.Category
+	err := row.Scan(&res.CategoryID, &res.ParentID, &res.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (postgre PostgreSQLManager) NewCategory(ctx context.Context, category models.CreatingCategory) (string, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	categoryID := uuid.New().String()
+	_, err := postgre.pool.Exec(ctx, "INSERT INTO categories (category_id, parent_id, name) VALUES ($1, $2, $3)", categoryID, category.ParentID, category.Name)
+	if err != nil {
+		return "", err
+	}
+	return categoryID, nil
+}
+
+func (postgre PostgreSQLManager) SelectCategory(ctx context.Context, categoryID string) (*models.Category, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	res, err := scanCategory(postgre.pool.QueryRow(ctx, "SELECT "+categoryColumns+" FROM categories WHERE category_id = $1", categoryID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return res, err
+}
+
+func (postgre PostgreSQLManager) GetAllCategories(ctx context.Context) ([]*models.Category, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	rows, err := postgre.pool.Query(ctx, "SELECT "+categoryColumns+" FROM categories ORDER BY name, category_id")
 	if err != nil {
 		return nil, err
 	} else {
 		defer rows.Close()
-		var result []*models.DbAd
+		result := []*models.Category{}
 		for rows.Next() {
-			var res models.DbAd
-			var tmp string
-			var createdAt int64
-			err := rows.Scan(&res.AdID, &res.Title, &res.Description, &res.Price, &tmp, &createdAt)
+			res, err := scanCategory(rows)
 			if err != nil {
 				return nil, err
-			} else {
-				res.CreatedAt = time.Unix(createdAt, 0)
-				err := json.Unmarshal([]byte(tmp), &res.PhotoLinks)
-				if err != nil {
-					return nil, err
-				} else {
-					result = append(result, &res)
-				}
 			}
+			result = append(result, res)
 		}
-		return result, err
+		return result, rows.Err()
+	}
+}
+
+func (postgre PostgreSQLManager) UpdateCategory(ctx context.Context, categoryID string, category models.CreatingCategory) (*models.Category, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	res, err := scanCategory(postgre.pool.QueryRow(ctx, "UPDATE categories SET name = $1, parent_id = $2 WHERE category_id = $3 RETURNING "+categoryColumns, category.Name, category.ParentID, categoryID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return res, err
+}
+
+func (postgre PostgreSQLManager) DeleteCategory(ctx context.Context, categoryID string) (bool, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	tag, err := postgre.pool.Exec(ctx, "DELETE FROM categories WHERE category_id = $1 AND NOT EXISTS (SELECT 1 FROM categories WHERE parent_id = $1) AND NOT EXISTS (SELECT 1 FROM ads WHERE category_id = $1)", categoryID)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 1 {
+		return true, nil
+	}
+	category, err := postgre.SelectCategory(ctx, categoryID)
+	if err != nil || category == nil {
+		return false, err
+	}
+	return false, ErrCategoryNotEmpty
+}
+
+const uniqueViolation = "23505"
+
+func (postgre PostgreSQLManager) NewUser(ctx context.Context, user models.CreatingUser) (string, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	userID := uuid.New().String()
+	_, err := postgre.pool.Exec(ctx, "INSERT INTO users (user_id, name, email, created_at) VALUES ($1, $2, $3, $4)", userID, user.Name, user.Email, currentTime())
+	if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == uniqueViolation {
+		return "", ErrEmailTaken
+	} else if err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+func (postgre PostgreSQLManager) SelectUser(ctx context.Context, userID string) (*models.User, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	var res models.User
+	err := postgre.pool.QueryRow(ctx, "SELECT user_id, name, email, created_at FROM users WHERE user_id = $1", userID).Scan(&res.UserID, &res.Name, &res.Email, &res.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	res.CreatedAt = res.CreatedAt.UTC()
+	return &res, nil
+}
+
+func (postgre PostgreSQLManager) ReserveIdempotencyKey(ctx context.Context, ownerID string, key string, requestHash string, expiresAt time.Time) (*models.IdempotencyRecord, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	// the record may be purged between the insert and the select, so the reservation is retried
+	for attempt := 0; attempt < 3; attempt++ {
+		tag, err := postgre.pool.Exec(ctx, `INSERT INTO idempotency_keys (owner_id, key, request_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (owner_id, key) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash, response_code = NULL, response_body = NULL, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= EXCLUDED.created_at`,
+			ownerID, key, requestHash, currentTime(), expiresAt)
+		if err != nil {
+			return nil, err
+		} else if tag.RowsAffected() == 1 {
+			return nil, nil
+		}
+		res := models.IdempotencyRecord{OwnerID: ownerID, Key: key}
+		var responseCode *int
+		err = postgre.pool.QueryRow(ctx, "SELECT request_hash, response_code, response_body, expires_at FROM idempotency_keys WHERE owner_id = $1 AND key = $2", ownerID, key).
+			Scan(&res.RequestHash, &responseCode, &res.ResponseBody, &res.ExpiresAt)
+		if err == pgx.ErrNoRows {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if responseCode != nil {
+			res.ResponseCode = *responseCode
+		}
+		res.ExpiresAt = res.ExpiresAt.UTC()
+		return &res, nil
+	}
+	return nil, fmt.Errorf("couldn't reserve idempotency key %q", key)
+}
+
+func (postgre PostgreSQLManager) CompleteIdempotencyKey(ctx context.Context, ownerID string, key string, responseCode int, responseBody []byte) error {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	_, err := postgre.pool.Exec(ctx, "UPDATE idempotency_keys SET response_code = $3, response_body = $4 WHERE owner_id = $1 AND key = $2", ownerID, key, responseCode, responseBody)
+	return err
+}
+
+func (postgre PostgreSQLManager) ReleaseIdempotencyKey(ctx context.Context, ownerID string, key string) error {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	_, err := postgre.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE owner_id = $1 AND key = $2 AND response_code IS NULL", ownerID, key)
+	return err
+}
+
+func (postgre PostgreSQLManager) PurgeIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	ctx, cancel := postgre.withQueryTimeout(ctx)
+	defer cancel()
+	tag, err := postgre.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE expires_at < $1", expiredBefore)
+	if err != nil {
+		return 0, err
 	}
+	return tag.RowsAffected(), nil
 }
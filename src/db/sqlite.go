@@ -0,0 +1,520 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+func init() {
+	// sqlite's lower() only folds ascii, which isn't enough for case-insensitive filtering of cyrillic ads
+	sqlite.MustRegisterDeterministicScalarFunction("unicode_lower", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		if text, ok := args[0].(string); ok {
+			return strings.ToLower(text), nil
+		}
+		return args[0], nil
+	})
+}
+
+// SQLiteManager stores timestamps as unix nanoseconds and photo links as a JSON array.
+type SQLiteManager struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewSQLiteManager(ctx context.Context, path string, queryTimeout time.Duration) (*SQLiteManager, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("can't open sqlite db: %s", err)
+	}
+	// sqlite allows a single writer, so one connection avoids busy errors when a read transaction is upgraded
+	db.SetMaxOpenConns(1)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("can't open sqlite db: %s", err)
+	}
+	return &SQLiteManager{db: db, queryTimeout: queryTimeout}, nil
+}
+
+func (manager SQLiteManager) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if manager.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, manager.queryTimeout)
+}
+
+func (manager SQLiteManager) PoolStats() PoolStats {
+	stat := manager.db.Stats()
+	return PoolStats{Acquired: int32(stat.InUse), Idle: int32(stat.Idle), Total: int32(stat.OpenConnections)}
+}
+
+func (manager SQLiteManager) Ping(ctx context.Context) error {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	return manager.db.PingContext(ctx)
+}
+
+func (manager SQLiteManager) Close() error {
+	return manager.db.Close()
+}
+
+func sqliteTime(value time.Time) int64 {
+	return value.UnixNano()
+}
+
+func fromSQLiteTime(value int64) time.Time {
+	return time.Unix(0, value).UTC()
+}
+
+const sqliteInsertAd = "INSERT INTO ads (ad_id, title, description, price, photo_links, category_id, owner_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+func sqliteAdArgs(adID string, adData models.CreatingAd, ownerID string, now int64) ([]interface{}, error) {
+	marshalledPhotoLinks, err := json.Marshal(adData.PhotoLinks)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{adID, adData.Title, adData.Description, adData.Price, string(marshalledPhotoLinks), nullableString(adData.CategoryID), ownerID, now, now}, nil
+}
+
+func (manager SQLiteManager) NewAd(ctx context.Context, adData models.CreatingAd, ownerID string) (string, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	adID := uuid.New().String()
+	args, err := sqliteAdArgs(adID, adData, ownerID, sqliteTime(currentTime()))
+	if err != nil {
+		return "", err
+	}
+	_, err = manager.db.ExecContext(ctx, sqliteInsertAd, args...)
+	if err != nil {
+		return "", err
+	}
+	return adID, nil
+}
+
+func (manager SQLiteManager) NewAds(ctx context.Context, ads []models.CreatingAd, ownerID string) ([]string, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	tx, err := manager.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, sqliteInsertAd)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	adIDs := make([]string, len(ads))
+	now := sqliteTime(currentTime())
+	for i, adData := range ads {
+		adIDs[i] = uuid.New().String()
+		args, err := sqliteAdArgs(adIDs[i], adData, ownerID, now)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return adIDs, nil
+}
+
+const sqliteAdColumns = "ads.ad_id, ads.title, ads.description, ads.price, ads.photo_links, ads.category_id, ads.owner_id, ads.created_at, ads.updated_at, ads.deleted_at"
+
+type sqliteRow interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSQLiteAd(row sqliteRow, extra ...interface{}) (*models.DbAd, error) {
+	var res models.DbAd
+	var photoLinks string
+	var categoryID, ownerID *string
+	var createdAt, updatedAt int64
+	var deletedAt *int64
+	err := row.Scan(append([]interface{}{&res.AdID, &res.Title, &res.Description, &res.Price, &photoLinks, &categoryID, &ownerID, &createdAt, &updatedAt, &deletedAt}, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(photoLinks), &res.PhotoLinks); err != nil {
+		return nil, err
+	}
+	if categoryID != nil {
+		res.CategoryID = *categoryID
+	}
+	if ownerID != nil {
+		res.OwnerID = *ownerID
+	}
+	res.CreatedAt = fromSQLiteTime(createdAt)
+	res.UpdatedAt = fromSQLiteTime(updatedAt)
+	if deletedAt != nil {
+		tmpDeletedAt := fromSQLiteTime(*deletedAt)
+		res.DeletedAt = &tmpDeletedAt
+	}
+	return &res, nil
+}
+
+func (manager SQLiteManager) SelectAd(ctx context.Context, adID string) (*models.DbAd, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	res, err := scanSQLiteAd(manager.db.QueryRowContext(ctx, "SELECT "+sqliteAdColumns+" FROM ads WHERE ad_id = ? AND deleted_at IS NULL", adID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return res, err
+}
+
+func sqliteAdsFilterClause(filter models.AdsFilter) (string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	add := func(condition string, values ...interface{}) {
+		args = append(args, values...)
+		conditions = append(conditions, condition)
+	}
+	if filter.MinPrice != nil {
+		add("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		add("price <= ?", *filter.MaxPrice)
+	}
+	if filter.CreatedAfter != nil {
+		add("created_at >= ?", sqliteTime(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		add("created_at <= ?", sqliteTime(*filter.CreatedBefore))
+	}
+	if filter.Query != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(filter.Query)) + "%"
+		add(`(unicode_lower(title) LIKE ? ESCAPE '\' OR unicode_lower(description) LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+	if filter.OwnerID != "" {
+		add("owner_id = ?", filter.OwnerID)
+	}
+	if filter.CategoryID != "" {
+		add("category_id IN (WITH RECURSIVE tree AS (SELECT category_id FROM categories WHERE category_id = ? UNION ALL SELECT categories.category_id FROM categories JOIN tree ON categories.parent_id = tree.category_id) SELECT category_id FROM tree)", filter.CategoryID)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func (manager SQLiteManager) GetAllAds(ctx context.Context, sortBy string, sortOrder string, page int, perPage int, filter models.AdsFilter, after *models.AdsCursor) ([]*models.DbAd, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	where, args := sqliteAdsFilterClause(filter)
+	offset := (page - 1) * perPage
+	if after != nil {
+		cursorValue := sqliteTime(after.CreatedAt)
+		if sortBy == "price" {
+			cursorValue = after.Price
+		}
+		comparison := "<"
+		if sortOrder == "asc" {
+			comparison = ">"
+		}
+		args = append(args, cursorValue, after.AdID)
+		where += fmt.Sprintf(" AND (%s, ad_id) %s (?, ?)", sortBy, comparison)
+		offset = 0
+	}
+	rows, err := manager.db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM ads WHERE %s ORDER BY %s %s, ad_id %[4]s LIMIT %d OFFSET %d", sqliteAdColumns, where, sortBy, sortOrder, perPage, offset), args...)
+	if err != nil {
+		return nil, err
+	} else {
+		defer rows.Close()
+		result := []*models.DbAd{}
+		for rows.Next() {
+			res, err := scanSQLiteAd(rows)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, res)
+		}
+		return result, rows.Err()
+	}
+}
+
+func (manager SQLiteManager) CountAds(ctx context.Context, filter models.AdsFilter) (int64, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	where, args := sqliteAdsFilterClause(filter)
+	var count int64
+	err := manager.db.QueryRowContext(ctx, "SELECT count(*) FROM ads WHERE "+where, args...).Scan(&count)
+	return count, err
+}
+
+// SearchAds requires every query word, like websearch_to_tsquery does for plain words. Title matches weigh more than description ones.
+func (manager SQLiteManager) SearchAds(ctx context.Context, query string, page int, perPage int, withSnippets bool) ([]*models.FoundAd, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	tokens := tokenize(query)
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	// tokens hold only letters and digits, so quoting them can't break the match syntax
+	match := `"` + strings.Join(tokens, `" "`) + `"`
+	snippet := "''"
+	if withSnippets {
+		snippet = "snippet(ads_search, -1, '<b>', '</b>', '', 35)"
+	}
+	rows, err := manager.db.QueryContext(ctx, fmt.Sprintf("SELECT %s, -bm25(ads_search, 1.0, 0.4) AS rank, %s FROM ads_search JOIN ads ON ads.id = ads_search.rowid WHERE ads_search MATCH ? AND ads.deleted_at IS NULL ORDER BY rank DESC, ads.ad_id LIMIT %d OFFSET %d", sqliteAdColumns, snippet, perPage, (page-1)*perPage), match)
+	if err != nil {
+		return nil, err
+	} else {
+		defer rows.Close()
+		result := []*models.FoundAd{}
+		for rows.Next() {
+			var rank float64
+			var snippet string
+			res, err := scanSQLiteAd(rows, &rank, &snippet)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, &models.FoundAd{DbAd: *res, Rank: rank, Snippet: snippet})
+		}
+		return result, rows.Err()
+	}
+}
+
+func (manager SQLiteManager) UpdateAd(ctx context.Context, adID string, adData models.UpdatingAd) (*models.DbAd, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	var columns []string
+	var args []interface{}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		columns = append(columns, column+" = ?")
+	}
+	if adData.Title != nil {
+		set("title", *adData.Title)
+	}
+	if adData.Description != nil {
+		set("description", *adData.Description)
+	}
+	if adData.Price != nil {
+		set("price", *adData.Price)
+	}
+	if adData.PhotoLinks != nil {
+		marshalledPhotoLinks, err := json.Marshal(*adData.PhotoLinks)
+		if err != nil {
+			return nil, err
+		}
+		set("photo_links", string(marshalledPhotoLinks))
+	}
+	if adData.CategoryID != nil {
+		set("category_id", nullableString(*adData.CategoryID))
+	}
+	set("updated_at", sqliteTime(currentTime()))
+	args = append(args, adID)
+	query := fmt.Sprintf("UPDATE ads SET %s WHERE ad_id = ? AND deleted_at IS NULL RETURNING %s", strings.Join(columns, ", "), strings.ReplaceAll(sqliteAdColumns, "ads.", ""))
+	res, err := scanSQLiteAd(manager.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return res, err
+}
+
+func (manager SQLiteManager) DeleteAd(ctx context.Context, adID string) (bool, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	res, err := manager.db.ExecContext(ctx, "UPDATE ads SET deleted_at = ? WHERE ad_id = ? AND deleted_at IS NULL", sqliteTime(currentTime()), adID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+func (manager SQLiteManager) RestoreAd(ctx context.Context, adID string) (bool, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	res, err := manager.db.ExecContext(ctx, "UPDATE ads SET deleted_at = NULL, updated_at = ? WHERE ad_id = ? AND deleted_at IS NOT NULL", sqliteTime(currentTime()), adID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+func (manager SQLiteManager) PurgeDeletedAds(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	res, err := manager.db.ExecContext(ctx, "DELETE FROM ads WHERE deleted_at < ?", sqliteTime(deletedBefore))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func scanSQLiteCategory(row sqliteRow) (*models.Category, error) {
+	var res models.Category
+	err := row.Scan(&res.CategoryID, &res.ParentID, &res.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (manager SQLiteManager) NewCategory(ctx context.Context, category models.CreatingCategory) (string, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	categoryID := uuid.New().String()
+	_, err := manager.db.ExecContext(ctx, "INSERT INTO categories (category_id, parent_id, name) VALUES (?, ?, ?)", categoryID, category.ParentID, category.Name)
+	if err != nil {
+		return "", err
+	}
+	return categoryID, nil
+}
+
+func (manager SQLiteManager) SelectCategory(ctx context.Context, categoryID string) (*models.Category, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	res, err := scanSQLiteCategory(manager.db.QueryRowContext(ctx, "SELECT "+categoryColumns+" FROM categories WHERE category_id = ?", categoryID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return res, err
+}
+
+func (manager SQLiteManager) GetAllCategories(ctx context.Context) ([]*models.Category, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	rows, err := manager.db.QueryContext(ctx, "SELECT "+categoryColumns+" FROM categories ORDER BY name, category_id")
+	if err != nil {
+		return nil, err
+	} else {
+		defer rows.Close()
+		result := []*models.Category{}
+		for rows.Next() {
+			res, err := scanSQLiteCategory(rows)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, res)
+		}
+		return result, rows.Err()
+	}
+}
+
+func (manager SQLiteManager) UpdateCategory(ctx context.Context, categoryID string, category models.CreatingCategory) (*models.Category, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	res, err := scanSQLiteCategory(manager.db.QueryRowContext(ctx, "UPDATE categories SET name = ?, parent_id = ? WHERE category_id = ? RETURNING "+categoryColumns, category.Name, category.ParentID, categoryID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return res, err
+}
+
+func (manager SQLiteManager) DeleteCategory(ctx context.Context, categoryID string) (bool, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	res, err := manager.db.ExecContext(ctx, "DELETE FROM categories WHERE category_id = ?1 AND NOT EXISTS (SELECT 1 FROM categories WHERE parent_id = ?1) AND NOT EXISTS (SELECT 1 FROM ads WHERE category_id = ?1)", categoryID)
+	if err != nil {
+		return false, err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 1 {
+		return err == nil, err
+	}
+	category, err := manager.SelectCategory(ctx, categoryID)
+	if err != nil || category == nil {
+		return false, err
+	}
+	return false, ErrCategoryNotEmpty
+}
+
+func (manager SQLiteManager) NewUser(ctx context.Context, user models.CreatingUser) (string, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	userID := uuid.New().String()
+	_, err := manager.db.ExecContext(ctx, "INSERT INTO users (user_id, name, email, email_key, created_at) VALUES (?, ?, ?, ?, ?)", userID, user.Name, user.Email, strings.ToLower(user.Email), sqliteTime(currentTime()))
+	if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return "", ErrEmailTaken
+	} else if err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+func (manager SQLiteManager) SelectUser(ctx context.Context, userID string) (*models.User, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	var res models.User
+	var createdAt int64
+	err := manager.db.QueryRowContext(ctx, "SELECT user_id, name, email, created_at FROM users WHERE user_id = ?", userID).Scan(&res.UserID, &res.Name, &res.Email, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	res.CreatedAt = fromSQLiteTime(createdAt)
+	return &res, nil
+}
+
+func (manager SQLiteManager) ReserveIdempotencyKey(ctx context.Context, ownerID string, key string, requestHash string, expiresAt time.Time) (*models.IdempotencyRecord, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	tx, err := manager.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, `INSERT INTO idempotency_keys (owner_id, key, request_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (owner_id, key) DO UPDATE
+		SET request_hash = excluded.request_hash, response_code = NULL, response_body = NULL, created_at = excluded.created_at, expires_at = excluded.expires_at
+		WHERE idempotency_keys.expires_at <= excluded.created_at`,
+		ownerID, key, requestHash, sqliteTime(currentTime()), sqliteTime(expiresAt.Truncate(time.Microsecond)))
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 1 {
+		return nil, tx.Commit()
+	}
+	record := models.IdempotencyRecord{OwnerID: ownerID, Key: key}
+	var responseCode *int
+	var expiresAtNano int64
+	err = tx.QueryRowContext(ctx, "SELECT request_hash, response_code, response_body, expires_at FROM idempotency_keys WHERE owner_id = ? AND key = ?", ownerID, key).
+		Scan(&record.RequestHash, &responseCode, &record.ResponseBody, &expiresAtNano)
+	if err != nil {
+		return nil, err
+	}
+	if responseCode != nil {
+		record.ResponseCode = *responseCode
+	}
+	record.ExpiresAt = fromSQLiteTime(expiresAtNano)
+	return &record, tx.Commit()
+}
+
+func (manager SQLiteManager) CompleteIdempotencyKey(ctx context.Context, ownerID string, key string, responseCode int, responseBody []byte) error {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	_, err := manager.db.ExecContext(ctx, "UPDATE idempotency_keys SET response_code = ?, response_body = ? WHERE owner_id = ? AND key = ?", responseCode, responseBody, ownerID, key)
+	return err
+}
+
+func (manager SQLiteManager) ReleaseIdempotencyKey(ctx context.Context, ownerID string, key string) error {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	_, err := manager.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE owner_id = ? AND key = ? AND response_code IS NULL", ownerID, key)
+	return err
+}
+
+func (manager SQLiteManager) PurgeIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	ctx, cancel := manager.withQueryTimeout(ctx)
+	defer cancel()
+	res, err := manager.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at < ?", sqliteTime(expiredBefore))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
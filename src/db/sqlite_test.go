@@ -0,0 +1,311 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"adv-backend-trainee-assignment/migrations"
+	"adv-backend-trainee-assignment/src/migrate"
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSQLiteManager(t *testing.T) *SQLiteManager {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ads.db")
+	loaded, err := migrate.Load(migrations.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	driver, err := migrate.NewSQLiteDriver(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator := migrate.New(driver, loaded)
+	defer migrator.Close()
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	manager, err := NewSQLiteManager(ctx, path, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		manager.Close()
+	})
+	return manager
+}
+
+func newTestSQLiteUser(t *testing.T, manager *SQLiteManager, email string) string {
+	userID, err := manager.NewUser(context.Background(), models.CreatingUser{Name: "user", Email: email})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userID
+}
+
+func TestSQLiteManager_Migrations(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ads.db")
+	loaded, err := migrate.Load(migrations.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	driver, err := migrate.NewSQLiteDriver(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator := migrate.New(driver, loaded)
+	defer migrator.Close()
+	applied, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, len(loaded), applied)
+	reverted, err := migrator.Down(ctx, len(loaded))
+	assert.NoError(t, err)
+	assert.Equal(t, len(loaded), reverted)
+	applied, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, len(loaded), applied)
+}
+
+func TestSQLiteManager_Ads(t *testing.T) {
+	manager := newTestSQLiteManager(t)
+	ctx := context.Background()
+	ownerID := newTestSQLiteUser(t, manager, "owner@example.com")
+	parentID, err := manager.NewCategory(ctx, models.CreatingCategory{Name: "transport"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	childID, err := manager.NewCategory(ctx, models.CreatingCategory{Name: "cars", ParentID: &parentID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var adIDs []string
+	for i, ad := range []models.CreatingAd{
+		{Title: "Велосипед", Description: "Горный", Price: 15000, PhotoLinks: []string{"https://ya.ru"}, CategoryID: parentID},
+		{Title: "Машина", Description: "Почти новая", Price: 3000000000, PhotoLinks: []string{"https://ya.ru", "https://example.com"}, CategoryID: childID},
+		{Title: "Диван", Description: "Синий", Price: 5000, PhotoLinks: []string{"https://example.com"}},
+	} {
+		adID, err := manager.NewAd(ctx, ad, ownerID)
+		if err != nil {
+			t.Fatalf("ad %d: %s", i, err)
+		}
+		adIDs = append(adIDs, adID)
+	}
+
+	selected, err := manager.SelectAd(ctx, adIDs[1])
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3000000000), selected.Price)
+	assert.Equal(t, []string{"https://ya.ru", "https://example.com"}, selected.PhotoLinks)
+	assert.Equal(t, childID, selected.CategoryID)
+	assert.Equal(t, ownerID, selected.OwnerID)
+	assert.WithinDuration(t, time.Now(), selected.CreatedAt, time.Minute)
+	missing, err := manager.SelectAd(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	titles := func(ads []*models.DbAd) []string {
+		var result []string
+		for _, ad := range ads {
+			result = append(result, ad.Title)
+		}
+		return result
+	}
+	ads, err := manager.GetAllAds(ctx, "price", "desc", 1, 2, models.AdsFilter{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Машина", "Велосипед"}, titles(ads))
+	ads, err = manager.GetAllAds(ctx, "price", "desc", 1, 2, models.AdsFilter{}, &models.AdsCursor{Price: ads[1].Price, AdID: ads[1].AdID})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Диван"}, titles(ads))
+	ads, err = manager.GetAllAds(ctx, "created_at", "asc", 2, 2, models.AdsFilter{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Диван"}, titles(ads))
+	ads, err = manager.GetAllAds(ctx, "created_at", "asc", 1, 10, models.AdsFilter{CategoryID: parentID}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Велосипед", "Машина"}, titles(ads))
+	minPrice := int64(10000)
+	ads, err = manager.GetAllAds(ctx, "price", "asc", 1, 10, models.AdsFilter{MinPrice: &minPrice, Query: "ГОРН"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Велосипед"}, titles(ads))
+	count, err := manager.CountAds(ctx, models.AdsFilter{MinPrice: &minPrice})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	title, price := "Диван угловой", int64(7000)
+	updated, err := manager.UpdateAd(ctx, adIDs[2], models.UpdatingAd{Title: &title, Price: &price})
+	assert.NoError(t, err)
+	assert.Equal(t, title, updated.Title)
+	assert.Equal(t, "Синий", updated.Description)
+	assert.Equal(t, price, updated.Price)
+	assert.True(t, !updated.UpdatedAt.Before(updated.CreatedAt))
+
+	deleted, err := manager.DeleteAd(ctx, adIDs[2])
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = manager.DeleteAd(ctx, adIDs[2])
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+	updated, err = manager.UpdateAd(ctx, adIDs[2], models.UpdatingAd{Title: &title})
+	assert.NoError(t, err)
+	assert.Nil(t, updated)
+	restored, err := manager.RestoreAd(ctx, adIDs[2])
+	assert.NoError(t, err)
+	assert.True(t, restored)
+	_, err = manager.DeleteAd(ctx, adIDs[2])
+	assert.NoError(t, err)
+	purged, err := manager.PurgeDeletedAds(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	count, err = manager.CountAds(ctx, models.AdsFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	_, err = manager.NewAd(ctx, models.CreatingAd{Title: "title", Description: "description", Price: 0, PhotoLinks: []string{"https://ya.ru"}}, ownerID)
+	assert.Error(t, err)
+	_, err = manager.NewAd(ctx, models.CreatingAd{Title: "title", Description: "description", Price: 1, PhotoLinks: []string{"https://ya.ru"}, CategoryID: "missing"}, ownerID)
+	assert.Error(t, err)
+}
+
+func TestSQLiteManager_NewAds(t *testing.T) {
+	manager := newTestSQLiteManager(t)
+	ctx := context.Background()
+	ownerID := newTestSQLiteUser(t, manager, "owner@example.com")
+	adIDs, err := manager.NewAds(ctx, []models.CreatingAd{
+		{Title: "title 1", Description: "description", Price: 1, PhotoLinks: []string{"https://ya.ru"}},
+		{Title: "title 2", Description: "description", Price: 2, PhotoLinks: []string{"https://ya.ru"}},
+	}, ownerID)
+	assert.NoError(t, err)
+	assert.Len(t, adIDs, 2)
+	_, err = manager.NewAds(ctx, []models.CreatingAd{
+		{Title: "title 3", Description: "description", Price: 3, PhotoLinks: []string{"https://ya.ru"}},
+		{Title: "title 4", Description: "description", Price: 4, PhotoLinks: []string{"https://ya.ru"}, CategoryID: "missing"},
+	}, ownerID)
+	assert.Error(t, err)
+	count, err := manager.CountAds(ctx, models.AdsFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestSQLiteManager_SearchAds(t *testing.T) {
+	manager := newTestSQLiteManager(t)
+	ctx := context.Background()
+	ownerID := newTestSQLiteUser(t, manager, "owner@example.com")
+	for _, ad := range []models.CreatingAd{
+		{Title: "Красный велосипед", Description: "Почти новый", Price: 1, PhotoLinks: []string{"https://ya.ru"}},
+		{Title: "Синий диван", Description: "Подойдёт к красному велосипеду", Price: 1, PhotoLinks: []string{"https://ya.ru"}},
+		{Title: "Красный велосипед детский", Description: "Красный", Price: 1, PhotoLinks: []string{"https://ya.ru"}},
+	} {
+		if _, err := manager.NewAd(ctx, ad, ownerID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	found, err := manager.SearchAds(ctx, "КРАСНЫЙ велосипед", 1, 10, true)
+	assert.NoError(t, err)
+	if assert.Len(t, found, 2) {
+		assert.Contains(t, found[0].Snippet, "<b>")
+		assert.True(t, found[0].Rank >= found[1].Rank)
+	}
+	found, err = manager.SearchAds(ctx, "красный", 2, 1, false)
+	assert.NoError(t, err)
+	if assert.Len(t, found, 1) {
+		assert.Empty(t, found[0].Snippet)
+	}
+	found, err = manager.SearchAds(ctx, `"*"`, 1, 10, false)
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func TestSQLiteManager_Categories(t *testing.T) {
+	manager := newTestSQLiteManager(t)
+	ctx := context.Background()
+	ownerID := newTestSQLiteUser(t, manager, "owner@example.com")
+	parentID, err := manager.NewCategory(ctx, models.CreatingCategory{Name: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	childID, err := manager.NewCategory(ctx, models.CreatingCategory{Name: "a", ParentID: &parentID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	categories, err := manager.GetAllCategories(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Category{{CategoryID: childID, ParentID: &parentID, Name: "a"}, {CategoryID: parentID, Name: "b"}}, categories)
+	updated, err := manager.UpdateCategory(ctx, childID, models.CreatingCategory{Name: "c"})
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Category{CategoryID: childID, Name: "c"}, updated)
+	_, err = manager.UpdateCategory(ctx, childID, models.CreatingCategory{Name: "c", ParentID: &parentID})
+	assert.NoError(t, err)
+
+	_, err = manager.DeleteCategory(ctx, parentID)
+	assert.Equal(t, ErrCategoryNotEmpty, err)
+	adID, err := manager.NewAd(ctx, models.CreatingAd{Title: "title", Description: "description", Price: 1, PhotoLinks: []string{"https://ya.ru"}, CategoryID: childID}, ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = manager.DeleteCategory(ctx, childID)
+	assert.Equal(t, ErrCategoryNotEmpty, err)
+	if _, err := manager.DeleteAd(ctx, adID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.PurgeDeletedAds(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := manager.DeleteCategory(ctx, childID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = manager.DeleteCategory(ctx, childID)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+	missing, err := manager.SelectCategory(ctx, childID)
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestSQLiteManager_Users(t *testing.T) {
+	manager := newTestSQLiteManager(t)
+	ctx := context.Background()
+	userID := newTestSQLiteUser(t, manager, "Пользователь@example.com")
+	user, err := manager.SelectUser(ctx, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Пользователь@example.com", user.Email)
+	_, err = manager.NewUser(ctx, models.CreatingUser{Name: "other", Email: "пользователь@EXAMPLE.com"})
+	assert.Equal(t, ErrEmailTaken, err)
+	missing, err := manager.SelectUser(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestSQLiteManager_IdempotencyKeys(t *testing.T) {
+	manager := newTestSQLiteManager(t)
+	ctx := context.Background()
+	ownerID := newTestSQLiteUser(t, manager, "owner@example.com")
+	expiresAt := time.Now().Add(time.Hour)
+
+	record, err := manager.ReserveIdempotencyKey(ctx, ownerID, "key", "hash", expiresAt)
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+	record, err = manager.ReserveIdempotencyKey(ctx, ownerID, "key", "other", expiresAt)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.IdempotencyRecord{OwnerID: ownerID, Key: "key", RequestHash: "hash", ExpiresAt: expiresAt.UTC().Truncate(time.Microsecond)}, record)
+
+	assert.NoError(t, manager.CompleteIdempotencyKey(ctx, ownerID, "key", 201, []byte(`{"adID":"id"}`)))
+	assert.NoError(t, manager.ReleaseIdempotencyKey(ctx, ownerID, "key"))
+	record, err = manager.ReserveIdempotencyKey(ctx, ownerID, "key", "hash", expiresAt)
+	assert.NoError(t, err)
+	assert.Equal(t, 201, record.ResponseCode)
+	assert.Equal(t, []byte(`{"adID":"id"}`), record.ResponseBody)
+
+	record, err = manager.ReserveIdempotencyKey(ctx, ownerID, "expiring", "hash", time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+	record, err = manager.ReserveIdempotencyKey(ctx, ownerID, "expiring", "other", expiresAt)
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+
+	assert.NoError(t, manager.ReleaseIdempotencyKey(ctx, ownerID, "expiring"))
+	purged, err := manager.PurgeIdempotencyKeys(ctx, expiresAt.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+}
@@ -0,0 +1,22 @@
+package logging
+
+import (
+	"context"
+	"time"
+
+	"adv-backend-trainee-assignment/src/db"
+	log "github.com/sirupsen/logrus"
+)
+
+// DBHook logs every call to a db.Instrumented connection with the request-scoped logger.
+func DBHook(ctx context.Context, call db.Call) (context.Context, func(err error)) {
+	started := time.Now()
+	return ctx, func(err error) {
+		logger := FromContext(ctx).WithFields(log.Fields{"db_method": call.Method, "db_latency_ms": float64(time.Since(started).Microseconds()) / 1000})
+		if err != nil {
+			logger.Warnf("db call failed. err: [%s]", err)
+		} else {
+			logger.Debug("db call finished")
+		}
+	}
+}
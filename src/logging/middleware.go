@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"net/http"
+	"time"
+
+	"adv-backend-trainee-assignment/src/tracing"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	RequestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (recorder *responseRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *responseRecorder) Write(data []byte) (int, error) {
+	n, err := recorder.ResponseWriter.Write(data)
+	recorder.bytes += n
+	return n, err
+}
+
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware propagates X-Request-ID, puts request-scoped logger into the context and writes access log.
+func Middleware(routeName string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			started := time.Now()
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = uuid.New().String()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+			fields := log.Fields{"request_id": requestID, "route": routeName}
+			if sc, ok := tracing.SpanContextFromContext(r.Context()); ok {
+				fields["trace_id"] = sc.TraceID.String()
+			}
+			logger := log.WithFields(fields)
+			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			h.ServeHTTP(recorder, r.WithContext(NewContext(r.Context(), logger)))
+			logger.WithFields(log.Fields{
+				"method":     r.Method,
+				"path":       r.URL.Path,
+				"status":     recorder.status,
+				"latency_ms": float64(time.Since(started).Microseconds()) / 1000,
+				"bytes":      recorder.bytes,
+				"remote":     r.RemoteAddr,
+			}).Info("request handled")
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/models"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func captureLogs(t *testing.T, level log.Level) *bytes.Buffer {
+	var buffer bytes.Buffer
+	logger := log.StandardLogger()
+	previousOut, previousFormatter, previousLevel := logger.Out, logger.Formatter, logger.Level
+	logger.SetOutput(&buffer)
+	logger.SetFormatter(&log.JSONFormatter{})
+	logger.SetLevel(level)
+	t.Cleanup(func() {
+		logger.SetOutput(previousOut)
+		logger.SetFormatter(previousFormatter)
+		logger.SetLevel(previousLevel)
+	})
+	return &buffer
+}
+
+func decodeLines(t *testing.T, buffer *bytes.Buffer) []map[string]interface{} {
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("unexpected log line %q: %v", line, err)
+		}
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name              string
+		requestID         string
+		expectedRequestID string
+	}{
+		{
+			name:              "Propagate request id",
+			requestID:         "gateway-42",
+			expectedRequestID: "gateway-42",
+		},
+		{
+			name: "Generate request id",
+		},
+		{
+			name:      "Replace invalid request id",
+			requestID: "bad id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := captureLogs(t, log.InfoLevel)
+			handler := Middleware("get ad")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				FromContext(r.Context()).Error("ad is broken")
+				http.Error(w, "ad not found", http.StatusNotFound)
+			}))
+			request, err := http.NewRequest(http.MethodGet, "/ads/1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.requestID != "" {
+				request.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, request)
+			requestID := rr.Header().Get(RequestIDHeader)
+			if tt.expectedRequestID != "" {
+				assert.Equal(t, tt.expectedRequestID, requestID)
+			} else {
+				assert.NotEmpty(t, requestID)
+				assert.NotEqual(t, tt.requestID, requestID)
+			}
+			lines := decodeLines(t, buffer)
+			assert.Equal(t, 2, len(lines))
+			assert.Equal(t, "ad is broken", lines[0]["msg"])
+			assert.Equal(t, requestID, lines[0]["request_id"])
+			access := lines[1]
+			assert.Equal(t, requestID, access["request_id"])
+			assert.Equal(t, "get ad", access["route"])
+			assert.Equal(t, http.MethodGet, access["method"])
+			assert.Equal(t, float64(http.StatusNotFound), access["status"])
+			assert.Equal(t, float64(len("ad not found\n")), access["bytes"])
+			assert.Contains(t, access, "latency_ms")
+		})
+	}
+}
+
+func TestDBHook(t *testing.T) {
+	buffer := captureLogs(t, log.DebugLevel)
+	logged := db.Instrumented(db.NewMockedDBManager(), DBHook)
+	defer logged.Close()
+	ctx := NewContext(context.Background(), log.WithField("request_id", "42"))
+	_, err := logged.CountAds(ctx, models.AdsFilter{})
+	assert.NoError(t, err)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = logged.SelectAd(canceled, "1")
+	assert.Error(t, err)
+	lines := decodeLines(t, buffer)
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, "debug", lines[0]["level"])
+	assert.Equal(t, "CountAds", lines[0]["db_method"])
+	assert.Equal(t, "42", lines[0]["request_id"])
+	assert.Equal(t, "warning", lines[1]["level"])
+	assert.Equal(t, "SelectAd", lines[1]["db_method"])
+}
+
+func TestConfigure(t *testing.T) {
+	captureLogs(t, log.InfoLevel)
+	assert.NoError(t, Configure("debug", "text"))
+	assert.Equal(t, log.DebugLevel, log.GetLevel())
+	assert.NoError(t, Configure("", ""))
+	assert.Equal(t, log.InfoLevel, log.GetLevel())
+	assert.Error(t, Configure("loud", "json"))
+	assert.Error(t, Configure("info", "xml"))
+}
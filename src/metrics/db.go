@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"adv-backend-trainee-assignment/src/db"
+)
+
+// DBHook records latency of every call to a db.Instrumented connection and counts created ads.
+func (m *Metrics) DBHook(ctx context.Context, call db.Call) (context.Context, func(err error)) {
+	started := time.Now()
+	return ctx, func(err error) {
+		status := "ok"
+		if err != nil {
+			status = "error"
+		}
+		m.dbQueryDuration.WithLabelValues(call.Method, status).Observe(time.Since(started).Seconds())
+		if err == nil && call.Rows > 0 {
+			m.adsCreated.Add(float64(call.Rows))
+		}
+	}
+}
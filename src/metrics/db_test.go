@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePool struct{}
+
+func (fakePool) PoolStats() db.PoolStats {
+	return db.PoolStats{Acquired: 1, Idle: 2, Total: 3}
+}
+
+func TestMetrics_DBHook(t *testing.T) {
+	m := New()
+	instrumented := db.Instrumented(db.NewMockedDBManager(), m.DBHook)
+	defer instrumented.Close()
+	ctx := context.Background()
+	categoryID, err := instrumented.NewCategory(ctx, models.CreatingCategory{Name: "category"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		_, err = instrumented.NewAd(ctx, models.CreatingAd{Title: "title", Description: "description", PhotoLinks: []string{"https://ya.ru"}, Price: 100, CategoryID: categoryID}, "owner")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = instrumented.NewAds(ctx, []models.CreatingAd{
+		{Title: "title", Description: "description", PhotoLinks: []string{"https://ya.ru"}, Price: 100, CategoryID: categoryID},
+		{Title: "title", Description: "description", PhotoLinks: []string{"https://ya.ru"}, Price: 100, CategoryID: categoryID},
+	}, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = instrumented.NewAd(canceled, models.CreatingAd{}, "owner")
+	assert.Error(t, err)
+
+	assert.Equal(t, float64(4), testutil.ToFloat64(m.adsCreated))
+	assert.Equal(t, 4, testutil.CollectAndCount(m.dbQueryDuration))
+
+	m.RegisterPoolStats(fakePool{})
+	rr := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Handler().ServeHTTP(rr, request)
+	body, err := ioutil.ReadAll(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, string(body), `adv_db_query_duration_seconds_count{method="NewAd",status="error"} 1`)
+	assert.Contains(t, string(body), `adv_db_query_duration_seconds_count{method="NewAd",status="ok"} 2`)
+	assert.Contains(t, string(body), `adv_db_query_duration_seconds_count{method="NewAds",status="ok"} 1`)
+	assert.Contains(t, string(body), "adv_ads_created_total 4")
+	assert.Contains(t, string(body), "adv_db_pool_acquired_connections 1")
+	assert.Contains(t, string(body), "adv_db_pool_idle_connections 2")
+	assert.Contains(t, string(body), "adv_db_pool_total_connections 3")
+}
@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (m *Metrics) Middleware(routeName string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			started := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			h.ServeHTTP(recorder, r)
+			m.httpRequests.WithLabelValues(routeName, r.Method, strconv.Itoa(recorder.status)).Inc()
+			m.httpDuration.WithLabelValues(routeName, r.Method).Observe(time.Since(started).Seconds())
+		})
+	}
+}
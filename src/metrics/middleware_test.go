@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_Middleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		route         string
+		status        int
+		expectedCount float64
+	}{
+		{
+			name:          "Successful request",
+			route:         "get ads",
+			status:        http.StatusOK,
+			expectedCount: 1,
+		},
+		{
+			name:          "Failed request",
+			route:         "create ad",
+			status:        http.StatusBadRequest,
+			expectedCount: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			handler := m.Middleware(tt.route)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			request, err := http.NewRequest(http.MethodGet, "/ads", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), request)
+			assert.Equal(t, tt.expectedCount, testutil.ToFloat64(m.httpRequests.WithLabelValues(tt.route, http.MethodGet, strconv.Itoa(tt.status))))
+			assert.Equal(t, 1, testutil.CollectAndCount(m.httpDuration))
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"adv-backend-trainee-assignment/src/db"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type PoolStatser interface {
+	PoolStats() db.PoolStats
+}
+
+func (m *Metrics) RegisterPoolStats(pool PoolStatser) {
+	gauge := func(name string, help string, value func(stats db.PoolStats) int32) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+		}, func() float64 {
+			return float64(value(pool.PoolStats()))
+		})
+	}
+	m.registry.MustRegister(
+		gauge("db_pool_acquired_connections", "Number of connections currently in use.", func(stats db.PoolStats) int32 { return stats.Acquired }),
+		gauge("db_pool_idle_connections", "Number of idle connections in the pool.", func(stats db.PoolStats) int32 { return stats.Idle }),
+		gauge("db_pool_total_connections", "Number of connections in the pool.", func(stats db.PoolStats) int32 { return stats.Total }),
+	)
+}
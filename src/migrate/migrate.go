@@ -0,0 +1,195 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Driver stores the schema version the same way golang-migrate does: a single version and a dirty flag,
+// so databases migrated with the migrate CLI keep working. Version 0 means no migrations are applied.
+type Driver interface {
+	Lock(ctx context.Context) error
+	Unlock(ctx context.Context) error
+	Version(ctx context.Context) (version uint64, dirty bool, err error)
+	SetVersion(ctx context.Context, version uint64, dirty bool) error
+	Exec(ctx context.Context, query string) error
+	Close() error
+}
+
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version uint64
+	Name    string
+	Applied bool
+}
+
+type Status struct {
+	Version    uint64
+	Dirty      bool
+	Migrations []MigrationStatus
+}
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Load reads <version>_<name>.up.sql and <version>_<name>.down.sql pairs from fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[uint64]*Migration)
+	for _, name := range names {
+		match := fileNamePattern.FindStringSubmatch(path.Base(name))
+		if match == nil {
+			return nil, fmt.Errorf("malformed migration file name %q", name)
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("malformed migration version in %q", name)
+		}
+		contents, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migrations %q and %q share version %d", migration.Name, match[2], version)
+		}
+		if match[3] == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s should have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+type Migrator struct {
+	driver     Driver
+	migrations []Migration
+}
+
+func New(driver Driver, migrations []Migration) *Migrator {
+	return &Migrator{driver: driver, migrations: migrations}
+}
+
+func (migrator *Migrator) Close() error {
+	return migrator.driver.Close()
+}
+
+// position returns the index of the first migration that isn't applied yet.
+func (migrator *Migrator) position(version uint64) (int, error) {
+	if version == 0 {
+		return 0, nil
+	}
+	for i, migration := range migrator.migrations {
+		if migration.Version == version {
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("database is at version %d, which isn't among known migrations", version)
+}
+
+// locked runs fn under the database lock and refuses to go on from a dirty version.
+func (migrator *Migrator) locked(ctx context.Context, fn func(position int) (int, error)) (int, error) {
+	if err := migrator.driver.Lock(ctx); err != nil {
+		return 0, fmt.Errorf("couldn't lock database: %s", err)
+	}
+	defer migrator.driver.Unlock(context.Background())
+	version, dirty, err := migrator.driver.Version(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("database is dirty at version %d, fix it by hand and reset the dirty flag in schema_migrations", version)
+	}
+	position, err := migrator.position(version)
+	if err != nil {
+		return 0, err
+	}
+	return fn(position)
+}
+
+// run marks the target version dirty until the migration succeeds, so a half-applied migration stops further runs.
+func (migrator *Migrator) run(ctx context.Context, query string, markVersion uint64, doneVersion uint64) error {
+	if err := migrator.driver.SetVersion(ctx, markVersion, true); err != nil {
+		return err
+	}
+	if err := migrator.driver.Exec(ctx, query); err != nil {
+		return err
+	}
+	return migrator.driver.SetVersion(ctx, doneVersion, false)
+}
+
+// Up applies all pending migrations and returns how many were applied.
+func (migrator *Migrator) Up(ctx context.Context) (int, error) {
+	return migrator.locked(ctx, func(position int) (int, error) {
+		applied := 0
+		for _, migration := range migrator.migrations[position:] {
+			if err := migrator.run(ctx, migration.Up, migration.Version, migration.Version); err != nil {
+				return applied, fmt.Errorf("couldn't apply migration %d_%s: %s", migration.Version, migration.Name, err)
+			}
+			applied++
+		}
+		return applied, nil
+	})
+}
+
+// Down reverts up to steps latest migrations and returns how many were reverted.
+func (migrator *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	return migrator.locked(ctx, func(position int) (int, error) {
+		reverted := 0
+		for i := position - 1; i >= 0 && reverted < steps; i-- {
+			migration := migrator.migrations[i]
+			var previous uint64
+			if i > 0 {
+				previous = migrator.migrations[i-1].Version
+			}
+			if err := migrator.run(ctx, migration.Down, migration.Version, previous); err != nil {
+				return reverted, fmt.Errorf("couldn't revert migration %d_%s: %s", migration.Version, migration.Name, err)
+			}
+			reverted++
+		}
+		return reverted, nil
+	})
+}
+
+func (migrator *Migrator) Status(ctx context.Context) (*Status, error) {
+	version, dirty, err := migrator.driver.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := Status{Version: version, Dirty: dirty}
+	for _, migration := range migrator.migrations {
+		status.Migrations = append(status.Migrations, MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+			Applied: migration.Version <= version && !(dirty && migration.Version == version),
+		})
+	}
+	return &status, nil
+}
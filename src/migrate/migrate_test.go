@@ -0,0 +1,164 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"adv-backend-trainee-assignment/migrations"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDriver struct {
+	version  uint64
+	dirty    bool
+	locked   bool
+	executed []string
+	failOn   string
+}
+
+func (driver *fakeDriver) Lock(context.Context) error {
+	if driver.locked {
+		return fmt.Errorf("already locked")
+	}
+	driver.locked = true
+	return nil
+}
+
+func (driver *fakeDriver) Unlock(context.Context) error {
+	driver.locked = false
+	return nil
+}
+
+func (driver *fakeDriver) Version(context.Context) (uint64, bool, error) {
+	return driver.version, driver.dirty, nil
+}
+
+func (driver *fakeDriver) SetVersion(_ context.Context, version uint64, dirty bool) error {
+	driver.version, driver.dirty = version, dirty
+	return nil
+}
+
+func (driver *fakeDriver) Exec(_ context.Context, query string) error {
+	if query == driver.failOn {
+		return fmt.Errorf("syntax error")
+	}
+	driver.executed = append(driver.executed, query)
+	return nil
+}
+
+func (driver *fakeDriver) Close() error {
+	return nil
+}
+
+var testFS = fstest.MapFS{
+	"2_second.up.sql":   {Data: []byte("up 2")},
+	"2_second.down.sql": {Data: []byte("down 2")},
+	"1_first.up.sql":    {Data: []byte("up 1")},
+	"1_first.down.sql":  {Data: []byte("down 1")},
+	"3_third.up.sql":    {Data: []byte("up 3")},
+	"3_third.down.sql":  {Data: []byte("down 3")},
+}
+
+func TestLoad(t *testing.T) {
+	loaded, err := Load(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []Migration{
+		{Version: 1, Name: "first", Up: "up 1", Down: "down 1"},
+		{Version: 2, Name: "second", Up: "up 2", Down: "down 2"},
+		{Version: 3, Name: "third", Up: "up 3", Down: "down 3"},
+	}, loaded)
+
+	tt := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"malformed name", fstest.MapFS{"first.up.sql": {Data: []byte("up")}}},
+		{"zero version", fstest.MapFS{"0_first.up.sql": {Data: []byte("up")}, "0_first.down.sql": {Data: []byte("down")}}},
+		{"missing down", fstest.MapFS{"1_first.up.sql": {Data: []byte("up")}}},
+		{"shared version", fstest.MapFS{"1_first.up.sql": {Data: []byte("up")}, "1_other.down.sql": {Data: []byte("down")}}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load(tc.fsys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadEmbedded(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, loaded)
+}
+
+func TestMigrator(t *testing.T) {
+	loaded, err := Load(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	driver := &fakeDriver{version: 1}
+	migrator := New(driver, loaded)
+
+	applied, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, applied)
+	assert.Equal(t, []string{"up 2", "up 3"}, driver.executed)
+	assert.Equal(t, uint64(3), driver.version)
+	assert.False(t, driver.locked)
+
+	applied, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, applied)
+
+	reverted, err := migrator.Down(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, reverted)
+	assert.Equal(t, []string{"up 2", "up 3", "down 3", "down 2"}, driver.executed)
+	assert.Equal(t, uint64(1), driver.version)
+
+	reverted, err = migrator.Down(ctx, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reverted)
+	assert.Equal(t, uint64(0), driver.version)
+
+	status, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, &Status{Migrations: []MigrationStatus{{1, "first", false}, {2, "second", false}, {3, "third", false}}}, status)
+}
+
+func TestMigratorFailure(t *testing.T) {
+	loaded, err := Load(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	driver := &fakeDriver{failOn: "up 2"}
+	migrator := New(driver, loaded)
+
+	applied, err := migrator.Up(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 1, applied)
+	assert.Equal(t, uint64(2), driver.version)
+	assert.True(t, driver.dirty)
+	assert.False(t, driver.locked)
+
+	status, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, &Status{Version: 2, Dirty: true, Migrations: []MigrationStatus{{1, "first", true}, {2, "second", false}, {3, "third", false}}}, status)
+
+	driver.failOn = ""
+	_, err = migrator.Up(ctx)
+	assert.Error(t, err)
+	_, err = migrator.Down(ctx, 1)
+	assert.Error(t, err)
+
+	_, err = New(&fakeDriver{version: 42}, loaded).Up(ctx)
+	assert.Error(t, err)
+}
@@ -0,0 +1,88 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// advisoryLockID keeps concurrently starting instances from applying the same migrations twice.
+const advisoryLockID int64 = 4821903375122417
+
+// nilVersion is how golang-migrate stores a dirty state before the first migration.
+const nilVersion int64 = -1
+
+// PostgreSQLDriver uses a single connection, because advisory locks belong to a session.
+type PostgreSQLDriver struct {
+	conn *pgx.Conn
+}
+
+func NewPostgreSQLDriver(ctx context.Context, dbUrl string) (*PostgreSQLDriver, error) {
+	conn, err := pgx.Connect(ctx, dbUrl)
+	if err != nil {
+		return nil, fmt.Errorf("can't open postgresql db: %s", err)
+	}
+	_, err = conn.Exec(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)")
+	if err != nil {
+		conn.Close(ctx)
+		return nil, err
+	}
+	return &PostgreSQLDriver{conn: conn}, nil
+}
+
+func (driver *PostgreSQLDriver) Lock(ctx context.Context) error {
+	_, err := driver.conn.Exec(ctx, "SELECT pg_advisory_lock($1)", advisoryLockID)
+	return err
+}
+
+func (driver *PostgreSQLDriver) Unlock(ctx context.Context) error {
+	_, err := driver.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", advisoryLockID)
+	return err
+}
+
+func (driver *PostgreSQLDriver) Version(ctx context.Context) (uint64, bool, error) {
+	var version int64
+	var dirty bool
+	err := driver.conn.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err == pgx.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+	if version == nilVersion {
+		return 0, dirty, nil
+	}
+	return uint64(version), dirty, nil
+}
+
+func (driver *PostgreSQLDriver) SetVersion(ctx context.Context, version uint64, dirty bool) error {
+	tx, err := driver.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, "TRUNCATE schema_migrations"); err != nil {
+		return err
+	}
+	if version > 0 || dirty {
+		stored := nilVersion
+		if version > 0 {
+			stored = int64(version)
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)", stored, dirty); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// Exec runs a whole migration file. Without arguments pgx uses the simple protocol, which allows several statements.
+func (driver *PostgreSQLDriver) Exec(ctx context.Context, query string) error {
+	_, err := driver.conn.Exec(ctx, query)
+	return err
+}
+
+func (driver *PostgreSQLDriver) Close() error {
+	return driver.conn.Close(context.Background())
+}
@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteDriver doesn't lock: a sqlite file is served by a single process, and writers are serialized by sqlite itself.
+type SQLiteDriver struct {
+	db *sql.DB
+}
+
+func NewSQLiteDriver(ctx context.Context, path string) (*SQLiteDriver, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("can't open sqlite db: %s", err)
+	}
+	db.SetMaxOpenConns(1)
+	_, err = db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version integer NOT NULL PRIMARY KEY, dirty integer NOT NULL)")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteDriver{db: db}, nil
+}
+
+func (driver *SQLiteDriver) Lock(ctx context.Context) error {
+	return nil
+}
+
+func (driver *SQLiteDriver) Unlock(ctx context.Context) error {
+	return nil
+}
+
+func (driver *SQLiteDriver) Version(ctx context.Context) (uint64, bool, error) {
+	var version int64
+	var dirty bool
+	err := driver.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+	if version == nilVersion {
+		return 0, dirty, nil
+	}
+	return uint64(version), dirty, nil
+}
+
+func (driver *SQLiteDriver) SetVersion(ctx context.Context, version uint64, dirty bool) error {
+	tx, err := driver.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+	if version > 0 || dirty {
+		stored := nilVersion
+		if version > 0 {
+			stored = int64(version)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)", stored, dirty); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (driver *SQLiteDriver) Exec(ctx context.Context, query string) error {
+	_, err := driver.db.ExecContext(ctx, query)
+	return err
+}
+
+func (driver *SQLiteDriver) Close() error {
+	return driver.db.Close()
+}
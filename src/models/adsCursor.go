@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type AdsCursor struct {
+	SortBy    string
+	SortOrder string
+	Price     int64
+	CreatedAt time.Time
+	AdID      string
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type AdsFilter struct {
+	MinPrice      *int64
+	MaxPrice      *int64
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Query         string
+	CategoryID    string
+	OwnerID       string
+}
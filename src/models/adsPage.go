@@ -0,0 +1,11 @@
+package models
+
+type AdsPage struct {
+	Items []*BasicAd `json:"items"`
+	// Total and Pages are nil on pages requested by cursor, which aren't counted.
+	Total      *int64 `json:"total,omitempty"`
+	Page       int    `json:"page"`
+	PerPage    int    `json:"perPage"`
+	Pages      *int64 `json:"pages,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
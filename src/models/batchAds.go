@@ -0,0 +1,19 @@
+package models
+
+type CreatingAds struct {
+	Ads []CreatingAd `json:"ads"`
+}
+
+// BatchAdResult holds either the id of the created ad or the reason it was rejected.
+type BatchAdResult struct {
+	Index  int          `json:"index"`
+	AdID   string       `json:"adID,omitempty"`
+	Code   string       `json:"code,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+type CreatedAds struct {
+	Created int             `json:"created"`
+	Failed  int             `json:"failed"`
+	Results []BatchAdResult `json:"results"`
+}
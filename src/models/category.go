@@ -0,0 +1,12 @@
+package models
+
+type Category struct {
+	CategoryID string  `json:"categoryID"`
+	ParentID   *string `json:"parentID,omitempty"`
+	Name       string  `json:"name"`
+}
+
+type CreatingCategory struct {
+	Name     string  `json:"name"`
+	ParentID *string `json:"parentID"`
+}
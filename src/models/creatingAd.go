@@ -5,6 +5,7 @@ type CreatingAd struct {
 	Price       int64    `json:"price"`
 	Description string   `json:"description"`
 	PhotoLinks  []string `json:"photoLinks"`
+	CategoryID  string   `json:"categoryID"`
 }
 
 type CreatedAd struct {
@@ -3,10 +3,14 @@ package models
 import "time"
 
 type DbAd struct {
-	AdID        string    `json:"ad_id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Price       int64     `json:"price"`
-	PhotoLinks  []string  `json:"photo_links"`
-	CreatedAt   time.Time `json:"created_at"`
+	AdID        string     `json:"ad_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Price       int64      `json:"price"`
+	PhotoLinks  []string   `json:"photo_links"`
+	CategoryID  string     `json:"category_id"`
+	OwnerID     string     `json:"owner_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
 }
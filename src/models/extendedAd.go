@@ -4,6 +4,8 @@ type ExtendedAd struct {
 	Title         string   `json:"title"`
 	Price         int64    `json:"price"`
 	MainPhotoLink string   `json:"mainPhotoLink"`
+	CategoryID    string   `json:"categoryID,omitempty"`
+	OwnerID       string   `json:"ownerID,omitempty"`
 	Description   string   `json:"description,omitempty"`
 	PhotoLinks    []string `json:"photoLinks,omitempty"`
 }
@@ -0,0 +1,13 @@
+package models
+
+type FoundAd struct {
+	DbAd
+	Rank    float64
+	Snippet string
+}
+
+type SearchedAd struct {
+	BasicAd
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet,omitempty"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type IdempotencyRecord struct {
+	OwnerID     string
+	Key         string
+	RequestHash string
+	// ResponseCode is zero while the original request is still in progress.
+	ResponseCode int
+	ResponseBody []byte
+	ExpiresAt    time.Time
+}
@@ -0,0 +1,5 @@
+package models
+
+type PurgedAds struct {
+	Purged int64 `json:"purged"`
+}
@@ -0,0 +1,9 @@
+package models
+
+type UpdatingAd struct {
+	Title       *string   `json:"title"`
+	Price       *int64    `json:"price"`
+	Description *string   `json:"description"`
+	PhotoLinks  *[]string `json:"photoLinks"`
+	CategoryID  *string   `json:"categoryID"`
+}
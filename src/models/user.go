@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type User struct {
+	UserID    string    `json:"userID"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PublicUser is what callers other than the user themself and moderators see.
+type PublicUser struct {
+	UserID    string    `json:"userID"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CreatingUser struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type CreatedUser struct {
+	UserID string `json:"userID"`
+}
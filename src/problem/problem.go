@@ -0,0 +1,44 @@
+// Package problem writes RFC 7807 error responses shared by the routes and the middlewares in front of them.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"adv-backend-trainee-assignment/src/models"
+)
+
+const (
+	CodeInvalidBody              = "invalid_body"
+	CodeBodyTooLarge             = "body_too_large"
+	CodeUnsupportedMediaType     = "unsupported_media_type"
+	CodeInvalidParameter         = "invalid_parameter"
+	CodeValidationFailed         = "validation_failed"
+	CodeUnknownCategory          = "unknown_category"
+	CodeUnauthorized             = "unauthorized"
+	CodeForbidden                = "forbidden"
+	CodeNotFound                 = "not_found"
+	CodeIdempotencyKeyReused     = "idempotency_key_reused"
+	CodeIdempotencyKeyInProgress = "idempotency_key_in_progress"
+	CodeEmailTaken               = "email_taken"
+	CodeCategoryNotEmpty         = "category_not_empty"
+	CodeDatabaseTimeout          = "database_timeout"
+	CodeRequestCanceled          = "request_canceled"
+	CodeInternal                 = "internal_error"
+	CodeServiceNotReady          = "service_not_ready"
+	ContentType                  = "application/problem+json"
+)
+
+func Write(w http.ResponseWriter, status int, code string, detail string, fieldErrors ...models.FieldError) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(models.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+		Errors: fieldErrors,
+	})
+}
@@ -0,0 +1,31 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	Write(recorder, http.StatusBadRequest, CodeValidationFailed, "exceeding data limitations", models.FieldError{Field: "title", Code: "too_long", Message: "too long"})
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, ContentType, recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "nosniff", recorder.Header().Get("X-Content-Type-Options"))
+	var body models.Problem
+	if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, models.Problem{
+		Type:   "about:blank",
+		Title:  "Bad Request",
+		Status: http.StatusBadRequest,
+		Code:   CodeValidationFailed,
+		Detail: "exceeding data limitations",
+		Errors: []models.FieldError{{Field: "title", Code: "too_long", Message: "too long"}},
+	}, body)
+}
@@ -0,0 +1,131 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/logging"
+	"adv-backend-trainee-assignment/src/models"
+	"adv-backend-trainee-assignment/src/problem"
+	"github.com/gorilla/mux"
+)
+
+func validCategoryName(name string) bool {
+	return 1 <= len(name) && len(name) <= 100
+}
+
+func (server APIServer) checkCategoryParent(w http.ResponseWriter, r *http.Request, categoryID string, parentID *string) bool {
+	if parentID == nil {
+		return true
+	}
+	if !server.checkCategory(w, r, *parentID) {
+		return false
+	}
+	if categoryID == "" {
+		return true
+	}
+	for currentID := parentID; currentID != nil; {
+		if *currentID == categoryID {
+			problem.Write(w, http.StatusBadRequest, problem.CodeValidationFailed, "category can't be a descendant of itself")
+			return false
+		}
+		category, err := server.DBManager.SelectCategory(r.Context(), *currentID)
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("couldn't get category with id %s from db. err: [%s]", *currentID, err)
+			writeDBError(w, err, "error getting category from db")
+			return false
+		} else if category == nil {
+			break
+		}
+		currentID = category.ParentID
+	}
+	return true
+}
+
+func (server APIServer) NewCategory(w http.ResponseWriter, r *http.Request) {
+	var category models.CreatingCategory
+	if !server.parseRequest(w, r, &category) {
+		return
+	}
+	if !validCategoryName(category.Name) {
+		problem.Write(w, http.StatusBadRequest, problem.CodeValidationFailed, "exceeding data limitations")
+		return
+	}
+	if !server.checkCategoryParent(w, r, "", category.ParentID) {
+		return
+	}
+	categoryID, err := server.DBManager.NewCategory(r.Context(), category)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("couldn't create category in db. err: [%s]", err)
+		writeDBError(w, err, "error creating category in db")
+	} else {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(models.Category{CategoryID: categoryID, ParentID: category.ParentID, Name: category.Name})
+	}
+}
+
+func (server APIServer) GetAllCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := server.DBManager.GetAllCategories(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("couldn't get categories from db. err: [%s]", err)
+		writeDBError(w, err, "error getting categories from db")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(categories)
+	}
+}
+
+func (server APIServer) SelectCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID := mux.Vars(r)["categoryID"]
+	category, err := server.DBManager.SelectCategory(r.Context(), categoryID)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("couldn't get category with id %s from db. err: [%s]", categoryID, err)
+		writeDBError(w, err, "error getting category from db")
+	} else if category == nil {
+		problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "category not found")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(category)
+	}
+}
+
+func (server APIServer) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID := mux.Vars(r)["categoryID"]
+	var category models.CreatingCategory
+	if !server.parseRequest(w, r, &category) {
+		return
+	}
+	if !validCategoryName(category.Name) {
+		problem.Write(w, http.StatusBadRequest, problem.CodeValidationFailed, "exceeding data limitations")
+		return
+	}
+	if !server.checkCategoryParent(w, r, categoryID, category.ParentID) {
+		return
+	}
+	updatedCategory, err := server.DBManager.UpdateCategory(r.Context(), categoryID, category)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("couldn't update category with id %s in db. err: [%s]", categoryID, err)
+		writeDBError(w, err, "error updating category in db")
+	} else if updatedCategory == nil {
+		problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "category not found")
+	} else {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(updatedCategory)
+	}
+}
+
+func (server APIServer) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID := mux.Vars(r)["categoryID"]
+	deleted, err := server.DBManager.DeleteCategory(r.Context(), categoryID)
+	if err == db.ErrCategoryNotEmpty {
+		problem.Write(w, http.StatusConflict, problem.CodeCategoryNotEmpty, err.Error())
+	} else if err != nil {
+		logging.FromContext(r.Context()).Errorf("couldn't delete category with id %s from db. err: [%s]", categoryID, err)
+		writeDBError(w, err, "error deleting category from db")
+	} else if !deleted {
+		problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "category not found")
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,116 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIServer_Categories(t *testing.T) {
+	type step struct {
+		method             string
+		urlFormat          string
+		body               string
+		expectedOutputCode int
+	}
+	tests := []struct {
+		server APIServer
+		name   string
+		steps  []step
+	}{
+		{
+			server: APIServer{DBManager: db.NewMockedDBManager()},
+			name:   "Create and get",
+			steps: []step{
+				{http.MethodPost, "/categories", `{"name":"cars"}`, http.StatusOK},
+				{http.MethodPost, "/categories", `{"name":"trucks","parentID":"{root}"}`, http.StatusOK},
+				{http.MethodGet, "/categories/{child}", "", http.StatusOK},
+				{http.MethodGet, "/categories", "", http.StatusOK},
+			},
+		},
+		{
+			server: APIServer{DBManager: db.NewMockedDBManager()},
+			name:   "Create with bad data",
+			steps: []step{
+				{http.MethodPost, "/categories", `{"name":""}`, http.StatusBadRequest},
+				{http.MethodPost, "/categories", `{"name":"trucks","parentID":"22e88a53-3c80-429d-9e84-99d217788098"}`, http.StatusBadRequest},
+				{http.MethodPost, "/categories", `{"name":`, http.StatusBadRequest},
+			},
+		},
+		{
+			server: APIServer{DBManager: db.NewMockedDBManager()},
+			name:   "Update",
+			steps: []step{
+				{http.MethodPut, "/categories/{grandchild}", `{"name":"renamed"}`, http.StatusOK},
+				{http.MethodPut, "/categories/{grandchild}", `{"name":"moved","parentID":"{root}"}`, http.StatusOK},
+				{http.MethodPut, "/categories/{root}", `{"name":"cycle","parentID":"{grandchild}"}`, http.StatusBadRequest},
+				{http.MethodPut, "/categories/{root}", `{"name":"self","parentID":"{root}"}`, http.StatusBadRequest},
+				{http.MethodPut, "/categories/22e88a53-3c80-429d-9e84-99d217788098", `{"name":"unknown"}`, http.StatusNotFound},
+			},
+		},
+		{
+			server: APIServer{DBManager: db.NewMockedDBManager()},
+			name:   "Delete",
+			steps: []step{
+				{http.MethodDelete, "/categories/{child}", "", http.StatusConflict},
+				{http.MethodDelete, "/categories/{grandchild}", "", http.StatusNoContent},
+				{http.MethodDelete, "/categories/{grandchild}", "", http.StatusNotFound},
+				{http.MethodGet, "/categories/{grandchild}", "", http.StatusNotFound},
+				{http.MethodDelete, "/categories/{child}", "", http.StatusNoContent},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.server.DBManager.Close()
+			router := mux.NewRouter()
+			router.HandleFunc("/categories", tt.server.NewCategory).Methods(http.MethodPost)
+			router.HandleFunc("/categories", tt.server.GetAllCategories).Methods(http.MethodGet)
+			router.HandleFunc("/categories/{categoryID}", tt.server.SelectCategory).Methods(http.MethodGet)
+			router.HandleFunc("/categories/{categoryID}", tt.server.UpdateCategory).Methods(http.MethodPut)
+			router.HandleFunc("/categories/{categoryID}", tt.server.DeleteCategory).Methods(http.MethodDelete)
+			root := populateCategory(t, tt.server)
+			child, err := tt.server.DBManager.NewCategory(context.Background(), models.CreatingCategory{Name: "child", ParentID: &root})
+			if err != nil {
+				t.Fatal(err)
+			}
+			grandchild, err := tt.server.DBManager.NewCategory(context.Background(), models.CreatingCategory{Name: "grandchild", ParentID: &child})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, step := range tt.steps {
+				replacer := strings.NewReplacer("{root}", root, "{child}", child, "{grandchild}", grandchild)
+				url := replacer.Replace(step.urlFormat)
+				body := replacer.Replace(step.body)
+				request, err := newRequest(step.method, url, bytes.NewBuffer([]byte(body)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, request)
+				assert.Equal(t, step.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code of %v %v: got %v expected %v", step.method, url, rr.Code, step.expectedOutputCode))
+				if rr.Code == http.StatusOK && step.method != http.MethodGet {
+					var category models.Category
+					err = json.Unmarshal(rr.Body.Bytes(), &category)
+					if err != nil {
+						t.Fatalf("unexpected output: %v", err)
+					}
+					var expected models.CreatingCategory
+					_ = json.Unmarshal([]byte(body), &expected)
+					assert.Equal(t, expected.Name, category.Name)
+					assert.Equal(t, expected.ParentID, category.ParentID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package routes
+
+import (
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"adv-backend-trainee-assignment/src/models"
+)
+
+// cursors travel in the query string, which Middleware lowercases, so they are
+// encoded with a case-insensitive alphabet
+var cursorEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type rawAdsCursor struct {
+	SortBy    string `json:"s"`
+	SortOrder string `json:"o"`
+	Value     int64  `json:"v"`
+	AdID      string `json:"id"`
+}
+
+func encodeAdsCursor(sortBy string, sortOrder string, lastAd *models.DbAd) string {
+	raw := rawAdsCursor{SortBy: sortBy, SortOrder: sortOrder, AdID: lastAd.AdID}
+	if sortBy == "price" {
+		raw.Value = lastAd.Price
+	} else {
+		raw.Value = lastAd.CreatedAt.UnixNano()
+	}
+	marshalled, _ := json.Marshal(raw)
+	return strings.ToLower(cursorEncoding.EncodeToString(marshalled))
+}
+
+func decodeAdsCursor(encoded string, sortBy string, sortOrder string) (*models.AdsCursor, error) {
+	marshalled, err := cursorEncoding.DecodeString(strings.ToUpper(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	var raw rawAdsCursor
+	err = json.Unmarshal(marshalled, &raw)
+	if err != nil || raw.AdID == "" {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	if raw.SortBy != sortBy || raw.SortOrder != sortOrder {
+		return nil, fmt.Errorf("cursor was issued for another sorting")
+	}
+	cursor := &models.AdsCursor{SortBy: raw.SortBy, SortOrder: raw.SortOrder, AdID: raw.AdID}
+	if sortBy == "price" {
+		cursor.Price = raw.Value
+	} else {
+		cursor.CreatedAt = time.Unix(0, raw.Value)
+	}
+	return cursor, nil
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"net/http"
+
+	"adv-backend-trainee-assignment/src/logging"
+	"adv-backend-trainee-assignment/src/problem"
+	"github.com/gorilla/mux"
+)
+
+func (server APIServer) DeleteAd(w http.ResponseWriter, r *http.Request) {
+	if adID, ok := mux.Vars(r)["adID"]; ok {
+		if !server.authorizeAdOwner(w, r, adID) {
+			return
+		}
+		deleted, err := server.DBManager.DeleteAd(r.Context(), adID)
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("couldn't delete ad with id %s from db. err: [%s]", adID, err)
+			writeDBError(w, err, "error deleting ad from db")
+		} else if !deleted {
+			problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "ad not found")
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	} else {
+		problem.Write(w, http.StatusBadRequest, problem.CodeInvalidParameter, "couldn't extract ad id from urlFormat")
+	}
+}
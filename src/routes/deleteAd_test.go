@@ -0,0 +1,107 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"adv-backend-trainee-assignment/src/db"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIServer_DeleteAd(t *testing.T) {
+	tests := []struct {
+		server             APIServer
+		name               string
+		population         string
+		deleteTimes        int
+		unknownAd          bool
+		expectedOutputCode int
+		expectedSelectCode int
+		expectedListLength int
+	}{
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Delete ad",
+			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru"],"price":100}`,
+			deleteTimes:        1,
+			expectedOutputCode: http.StatusNoContent,
+			expectedSelectCode: http.StatusNotFound,
+			expectedListLength: 0,
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Delete already deleted ad",
+			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru"],"price":100}`,
+			deleteTimes:        2,
+			expectedOutputCode: http.StatusNotFound,
+			expectedSelectCode: http.StatusNotFound,
+			expectedListLength: 0,
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Delete not existing ad",
+			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru"],"price":100}`,
+			deleteTimes:        1,
+			unknownAd:          true,
+			expectedOutputCode: http.StatusNotFound,
+			expectedListLength: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.server.DBManager.Close()
+			router := mux.NewRouter()
+			router.HandleFunc("/ads/{adID}", tt.server.DeleteAd).Methods(http.MethodDelete)
+			router.HandleFunc("/ads/{adID}", tt.server.SelectAd).Methods(http.MethodGet)
+			router.HandleFunc("/ads", tt.server.GetAllAds).Methods(http.MethodGet)
+			ownerID := populateUser(t, tt.server)
+			request, err := newRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(withCategory(t, tt.population, populateCategory(t, tt.server)))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			tt.server.NewAd(rr, asUser(request, ownerID))
+			var tmpData map[string]interface{}
+			err = json.Unmarshal(rr.Body.Bytes(), &tmpData)
+			if err != nil {
+				t.Fatalf("unexpected output: %v", err)
+			}
+			adID := tmpData["ad_id"]
+			if tt.unknownAd {
+				adID = "22e88a53-3c80-429d-9e84-99d217788098"
+			}
+			for i := 0; i < tt.deleteTimes; i++ {
+				request, err = newRequest(http.MethodDelete, fmt.Sprintf("/ads/%v", adID), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr = httptest.NewRecorder()
+				router.ServeHTTP(rr, asUser(request, ownerID))
+			}
+			assert.Equal(t, tt.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode))
+			if tt.expectedSelectCode != 0 {
+				request, err = newRequest(http.MethodGet, fmt.Sprintf("/ads/%v", adID), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr = httptest.NewRecorder()
+				router.ServeHTTP(rr, request)
+				assert.Equal(t, tt.expectedSelectCode, rr.Code, "deleted ad is still visible")
+			}
+			request, err = newRequest(http.MethodGet, "/ads", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr = httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+			var list []map[string]interface{}
+			_ = json.Unmarshal(rr.Body.Bytes(), &list)
+			assert.Equal(t, tt.expectedListLength, len(list), fmt.Sprintf("wrong output length: got %v expected %v", len(list), tt.expectedListLength))
+		})
+	}
+}
@@ -2,11 +2,17 @@ package routes
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"adv-backend-trainee-assignment/src/logging"
 	"adv-backend-trainee-assignment/src/models"
-	log "github.com/sirupsen/logrus"
+	"adv-backend-trainee-assignment/src/problem"
+	"github.com/gorilla/mux"
 )
 
 func convertDBAdToBasicAd(dbAd *models.DbAd) *models.BasicAd {
@@ -18,27 +24,51 @@ func convertDBAdToBasicAd(dbAd *models.DbAd) *models.BasicAd {
 	}
 }
 
-func (server APIServer) GetAllAds(w http.ResponseWriter, r *http.Request) {
-	var err error
-	q := r.URL.Query()
-	sortBy := q.Get("sortby")
-	if sortBy == "createdat" {
-		sortBy = "created_at"
+func convertDBAdsToBasicAds(dbAds []*models.DbAd) []*models.BasicAd {
+	result := make([]*models.BasicAd, 0, len(dbAds))
+	for _, dbAd := range dbAds {
+		result = append(result, convertDBAdToBasicAd(dbAd))
 	}
-	if !(sortBy == "created_at" || sortBy == "price") {
-		sortBy = "created_at"
+	return result
+}
+
+func parseInt64Param(q url.Values, name string) (*int64, error) {
+	raw := q.Get(name)
+	if raw == "" {
+		return nil, nil
 	}
-	sortDirection := q.Get("sortdirection")
-	if !(sortDirection == "asc" || sortDirection == "desc") {
-		sortDirection = "desc"
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s should be an integer", name)
+	}
+	return &value, nil
+}
+
+func parseTimeParam(q url.Values, name string) (*time.Time, error) {
+	raw := q.Get(name)
+	if raw == "" {
+		return nil, nil
 	}
+	if unixTime, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		value := time.Unix(unixTime, 0)
+		return &value, nil
+	}
+	value, err := time.Parse(time.RFC3339, strings.ToUpper(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%s should be a unix timestamp or RFC 3339 date", name)
+	}
+	return &value, nil
+}
+
+func parsePagination(q url.Values) (int, int) {
+	var err error
 	pageStr := q.Get("page")
 	var page int
 	if pageStr == "" {
 		page = 1
 	} else {
 		page, err = strconv.Atoi(pageStr)
-		if err != nil {
+		if err != nil || page < 1 {
 			page = 1
 		}
 	}
@@ -57,20 +87,127 @@ func (server APIServer) GetAllAds(w http.ResponseWriter, r *http.Request) {
 	} else if perPage < 1 {
 		perPage = 1
 	}
-	adData, err := server.DBManager.GetAllAds(sortBy, sortDirection, page, perPage)
+	return page, perPage
+}
+
+func parseAdsFilter(q url.Values) (models.AdsFilter, error) {
+	var filter models.AdsFilter
+	var err error
+	if filter.MinPrice, err = parseInt64Param(q, "minprice"); err != nil {
+		return filter, err
+	}
+	if filter.MaxPrice, err = parseInt64Param(q, "maxprice"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedAfter, err = parseTimeParam(q, "createdafter"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedBefore, err = parseTimeParam(q, "createdbefore"); err != nil {
+		return filter, err
+	}
+	filter.Query = strings.TrimSpace(q.Get("q"))
+	filter.CategoryID = q.Get("category")
+	return filter, nil
+}
+
+func (server APIServer) GetAllAds(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	sortBy := q.Get("sortby")
+	if sortBy == "createdat" {
+		sortBy = "created_at"
+	}
+	if !(sortBy == "created_at" || sortBy == "price") {
+		sortBy = "created_at"
+	}
+	sortDirection := q.Get("sortdirection")
+	if !(sortDirection == "asc" || sortDirection == "desc") {
+		sortDirection = "desc"
+	}
+	page, perPage := parsePagination(q)
+	filter, err := parseAdsFilter(q)
 	if err != nil {
-		log.Errorf("couldn't get ads from db. err: [%s]", err)
-		http.Error(w, "error getting ads from db", http.StatusInternalServerError)
+		problem.Write(w, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+		return
+	}
+	filter.OwnerID = mux.Vars(r)["userID"]
+	var cursor *models.AdsCursor
+	if rawCursor := q.Get("cursor"); rawCursor != "" {
+		cursor, err = decodeAdsCursor(rawCursor, sortBy, sortDirection)
+		if err != nil {
+			problem.Write(w, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+			return
+		}
+	}
+	resp := models.AdsPage{Page: page, PerPage: perPage}
+	if cursor != nil {
+		// keyset pages skip the count, which would scan every matching ad, and look one ad ahead to find the end instead
+		adData, err := server.DBManager.GetAllAds(r.Context(), sortBy, sortDirection, page, perPage+1, filter, cursor)
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("couldn't get ads from db. err: [%s]", err)
+			writeDBError(w, err, "error getting ads from db")
+			return
+		}
+		if len(adData) > perPage {
+			adData = adData[:perPage]
+			resp.NextCursor = encodeAdsCursor(sortBy, sortDirection, adData[len(adData)-1])
+		}
+		resp.Items = convertDBAdsToBasicAds(adData)
 	} else {
-		w.Header().Set("Content-Type", "application/json")
-		var resp []*models.BasicAd
-		if adData != nil {
-			for _, tmpData := range adData {
-				resp = append(resp, convertDBAdToBasicAd(tmpData))
-			}
-			_ = json.NewEncoder(w).Encode(resp)
-		} else {
-			_, _ = w.Write([]byte("{}"))
+		adData, err := server.DBManager.GetAllAds(r.Context(), sortBy, sortDirection, page, perPage, filter, nil)
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("couldn't get ads from db. err: [%s]", err)
+			writeDBError(w, err, "error getting ads from db")
+			return
+		}
+		total, err := server.DBManager.CountAds(r.Context(), filter)
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("couldn't count ads in db. err: [%s]", err)
+			writeDBError(w, err, "error counting ads in db")
+			return
+		}
+		pages := (total + int64(perPage) - 1) / int64(perPage)
+		resp.Total, resp.Pages = &total, &pages
+		if len(adData) > 0 && int64(page)*int64(perPage) < total {
+			resp.NextCursor = encodeAdsCursor(sortBy, sortDirection, adData[len(adData)-1])
 		}
+		resp.Items = convertDBAdsToBasicAds(adData)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if q.Get("envelope") == "true" {
+		_ = json.NewEncoder(w).Encode(resp)
+	} else {
+		setPaginationHeaders(w, r, resp)
+		_ = json.NewEncoder(w).Encode(resp.Items)
+	}
+}
+
+func setPaginationHeaders(w http.ResponseWriter, r *http.Request, resp models.AdsPage) {
+	if resp.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", resp.NextCursor)
+	}
+	// keyset pages aren't counted, so they have neither the total nor page links
+	if resp.Total == nil {
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.FormatInt(*resp.Total, 10))
+	pageLink := func(page int64, rel string) string {
+		q := r.URL.Query()
+		q.Del("cursor")
+		q.Set("page", strconv.FormatInt(page, 10))
+		q.Set("perpage", strconv.Itoa(resp.PerPage))
+		return fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, q.Encode(), rel)
+	}
+	lastPage := *resp.Pages
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	links := []string{pageLink(1, "first")}
+	if resp.Page > 1 && int64(resp.Page) <= lastPage {
+		links = append(links, pageLink(int64(resp.Page-1), "prev"))
+	}
+	if int64(resp.Page) < lastPage {
+		links = append(links, pageLink(int64(resp.Page+1), "next"))
 	}
+	links = append(links, pageLink(lastPage, "last"))
+	w.Header().Set("Link", strings.Join(links, ", "))
 }
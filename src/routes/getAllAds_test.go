@@ -2,6 +2,7 @@ package routes
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/models"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,7 +27,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 		expectedOutputOrder  []int
 	}{
 		{
-			server: APIServer{db.NewMockedDBManager()},
+			server: APIServer{DBManager: db.NewMockedDBManager()},
 			name:   "Get all no filters",
 			url:    "/ads?page=1&perPage=10",
 			population: []string{
@@ -38,7 +40,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 			expectedOutputOrder:  []int{3, 2, 1},
 		},
 		{
-			server: APIServer{db.NewMockedDBManager()},
+			server: APIServer{DBManager: db.NewMockedDBManager()},
 			name:   "Get all order by createdAt",
 			url:    "/ads?page=1&perPage=10&sortBy=createdAt",
 			population: []string{
@@ -51,7 +53,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 			expectedOutputOrder:  []int{3, 2, 1},
 		},
 		{
-			server: APIServer{db.NewMockedDBManager()},
+			server: APIServer{DBManager: db.NewMockedDBManager()},
 			name:   "Get all order by createdAt ASC",
 			url:    "/ads?page=1&perPage=10&sortBy=createdAt&sortDirection=asc",
 			population: []string{
@@ -64,7 +66,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 			expectedOutputOrder:  []int{1, 2, 3},
 		},
 		{
-			server: APIServer{db.NewMockedDBManager()},
+			server: APIServer{DBManager: db.NewMockedDBManager()},
 			name:   "Get all order by createdAt DESC",
 			url:    "/ads?page=1&perPage=10&sortBy=createdAt&sortDirection=desc",
 			population: []string{
@@ -77,7 +79,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 			expectedOutputOrder:  []int{3, 2, 1},
 		},
 		{
-			server: APIServer{db.NewMockedDBManager()},
+			server: APIServer{DBManager: db.NewMockedDBManager()},
 			name:   "Get all order by price ASC",
 			url:    "/ads?page=1&perPage=10&sortBy=price&sortDirection=asc",
 			population: []string{
@@ -90,7 +92,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 			expectedOutputOrder:  []int{3, 1, 2},
 		},
 		{
-			server: APIServer{db.NewMockedDBManager()},
+			server: APIServer{DBManager: db.NewMockedDBManager()},
 			name:   "Get all order by price DESC",
 			url:    "/ads?page=1&perPage=10&sortBy=price&sortDirection=desc",
 			population: []string{
@@ -103,7 +105,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 			expectedOutputOrder:  []int{2, 1, 3},
 		},
 		{
-			server: APIServer{db.NewMockedDBManager()},
+			server: APIServer{DBManager: db.NewMockedDBManager()},
 			name:   "Get 1 per page on 1st page",
 			url:    "/ads?page=1&perPage=1",
 			population: []string{
@@ -116,7 +118,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 			expectedOutputOrder:  []int{3},
 		},
 		{
-			server: APIServer{db.NewMockedDBManager()},
+			server: APIServer{DBManager: db.NewMockedDBManager()},
 			name:   "Get 1 per page on 2nd page",
 			url:    "/ads?page=2&perPage=1",
 			population: []string{
@@ -129,7 +131,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 			expectedOutputOrder:  []int{2},
 		},
 		{
-			server: APIServer{db.NewMockedDBManager()},
+			server: APIServer{DBManager: db.NewMockedDBManager()},
 			name:   "Exceed pages",
 			url:    "/ads?page=3&perPage=2",
 			population: []string{
@@ -142,7 +144,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 			expectedOutputOrder:  []int{},
 		},
 		{
-			server: APIServer{db.NewMockedDBManager()},
+			server: APIServer{DBManager: db.NewMockedDBManager()},
 			name:   "No per page parameter",
 			url:    "/ads?page=3",
 			population: []string{
@@ -155,7 +157,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 			expectedOutputOrder:  []int{},
 		},
 		{
-			server: APIServer{db.NewMockedDBManager()},
+			server: APIServer{DBManager: db.NewMockedDBManager()},
 			name:   "No page parameter",
 			url:    "/ads",
 			population: []string{
@@ -168,7 +170,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 			expectedOutputOrder:  []int{3, 2, 1},
 		},
 		{
-			server: APIServer{db.NewMockedDBManager()},
+			server: APIServer{DBManager: db.NewMockedDBManager()},
 			name:   "Page parameter is not int",
 			url:    "/ads?page=avb",
 			population: []string{
@@ -181,7 +183,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 			expectedOutputOrder:  []int{3, 2, 1},
 		},
 		{
-			server: APIServer{db.NewMockedDBManager()},
+			server: APIServer{DBManager: db.NewMockedDBManager()},
 			name:   "Per page parameter is not int",
 			url:    "/ads?perPage=avb",
 			population: []string{
@@ -194,7 +196,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 			expectedOutputOrder:  []int{3, 2, 1},
 		},
 		{
-			server: APIServer{db.NewMockedDBManager()},
+			server: APIServer{DBManager: db.NewMockedDBManager()},
 			name:   "Per page parameter is too big",
 			url:    "/ads?perPage=1000",
 			population: []string{
@@ -207,7 +209,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 			expectedOutputOrder:  []int{3, 2, 1},
 		},
 		{
-			server: APIServer{db.NewMockedDBManager()},
+			server: APIServer{DBManager: db.NewMockedDBManager()},
 			name:   "Per page parameter is too small",
 			url:    "/ads?perPage=-1000",
 			population: []string{
@@ -219,19 +221,125 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 			expectedOutputLength: 1,
 			expectedOutputOrder:  []int{3},
 		},
+		{
+			server: APIServer{DBManager: db.NewMockedDBManager()},
+			name:   "Filter by price range",
+			url:    "/ads?minPrice=20&maxPrice=110",
+			population: []string{
+				`{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":100}`,
+				`{"title":"title 2","description":"description 2","photoLinks":["http://google.com"],"price":123}`,
+				`{"title":"title 3","description":"description 3","photoLinks":["http://google.com"],"price":15}`,
+			},
+			expectedOutputCode:   http.StatusOK,
+			expectedOutputLength: 1,
+			expectedOutputOrder:  []int{1},
+		},
+		{
+			server: APIServer{DBManager: db.NewMockedDBManager()},
+			name:   "Filter by min price",
+			url:    "/ads?minPrice=100&sortBy=price&sortDirection=asc",
+			population: []string{
+				`{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":100}`,
+				`{"title":"title 2","description":"description 2","photoLinks":["http://google.com"],"price":123}`,
+				`{"title":"title 3","description":"description 3","photoLinks":["http://google.com"],"price":15}`,
+			},
+			expectedOutputCode:   http.StatusOK,
+			expectedOutputLength: 2,
+			expectedOutputOrder:  []int{1, 2},
+		},
+		{
+			server: APIServer{DBManager: db.NewMockedDBManager()},
+			name:   "Filter by keyword",
+			url:    "/ads?q=Title%202",
+			population: []string{
+				`{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":100}`,
+				`{"title":"title 2","description":"description 2","photoLinks":["http://google.com"],"price":123}`,
+				`{"title":"title 3","description":"description 3","photoLinks":["http://google.com"],"price":15}`,
+			},
+			expectedOutputCode:   http.StatusOK,
+			expectedOutputLength: 1,
+			expectedOutputOrder:  []int{2},
+		},
+		{
+			server: APIServer{DBManager: db.NewMockedDBManager()},
+			name:   "Filter by keyword in description",
+			url:    "/ads?q=DESCRIPTION&sortDirection=asc",
+			population: []string{
+				`{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":100}`,
+				`{"title":"title 2","description":"description 2","photoLinks":["http://google.com"],"price":123}`,
+				`{"title":"title 3","description":"description 3","photoLinks":["http://google.com"],"price":15}`,
+			},
+			expectedOutputCode:   http.StatusOK,
+			expectedOutputLength: 3,
+			expectedOutputOrder:  []int{1, 2, 3},
+		},
+		{
+			server: APIServer{DBManager: db.NewMockedDBManager()},
+			name:   "Filter by creation date",
+			url:    "/ads?createdAfter=2000-01-01T00:00:00Z&createdBefore=4102444800",
+			population: []string{
+				`{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":100}`,
+				`{"title":"title 2","description":"description 2","photoLinks":["http://google.com"],"price":123}`,
+				`{"title":"title 3","description":"description 3","photoLinks":["http://google.com"],"price":15}`,
+			},
+			expectedOutputCode:   http.StatusOK,
+			expectedOutputLength: 3,
+			expectedOutputOrder:  []int{3, 2, 1},
+		},
+		{
+			server: APIServer{DBManager: db.NewMockedDBManager()},
+			name:   "Filter by creation date in future",
+			url:    "/ads?createdAfter=4102444800",
+			population: []string{
+				`{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":100}`,
+				`{"title":"title 2","description":"description 2","photoLinks":["http://google.com"],"price":123}`,
+				`{"title":"title 3","description":"description 3","photoLinks":["http://google.com"],"price":15}`,
+			},
+			expectedOutputCode:   http.StatusOK,
+			expectedOutputLength: 0,
+			expectedOutputOrder:  []int{},
+		},
+		{
+			server: APIServer{DBManager: db.NewMockedDBManager()},
+			name:   "Bad min price",
+			url:    "/ads?minPrice=cheap",
+			population: []string{
+				`{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":100}`,
+				`{"title":"title 2","description":"description 2","photoLinks":["http://google.com"],"price":123}`,
+				`{"title":"title 3","description":"description 3","photoLinks":["http://google.com"],"price":15}`,
+			},
+			expectedOutputCode:   http.StatusBadRequest,
+			expectedOutputLength: 0,
+			expectedOutputOrder:  []int{},
+		},
+		{
+			server: APIServer{DBManager: db.NewMockedDBManager()},
+			name:   "Bad creation date",
+			url:    "/ads?createdBefore=yesterday",
+			population: []string{
+				`{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":100}`,
+				`{"title":"title 2","description":"description 2","photoLinks":["http://google.com"],"price":123}`,
+				`{"title":"title 3","description":"description 3","photoLinks":["http://google.com"],"price":15}`,
+			},
+			expectedOutputCode:   http.StatusBadRequest,
+			expectedOutputLength: 0,
+			expectedOutputOrder:  []int{},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			defer tt.server.DBManager.Close()
+			categoryID := populateCategory(t, tt.server)
+			ownerID := populateUser(t, tt.server)
 			population := make([]string, len(tt.population))
 			i := 0
 			for _, insertData := range tt.population {
-				request, err := http.NewRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(insertData)))
+				request, err := newRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(withCategory(t, insertData, categoryID))))
 				if err != nil {
 					t.Fatal(err)
 				}
 				rr := httptest.NewRecorder()
-				tt.server.NewAd(rr, request)
+				tt.server.NewAd(rr, asUser(request, ownerID))
 				if rr.Code != http.StatusOK {
 					t.Errorf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode)
 				} else {
@@ -245,7 +353,7 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 				}
 				time.Sleep(time.Nanosecond) // without sleep insertions are too fast
 			}
-			request, err := http.NewRequest(http.MethodGet, strings.ToLower(tt.url), nil)
+			request, err := newRequest(http.MethodGet, strings.ToLower(tt.url), nil)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -265,3 +373,336 @@ func TestAPIServer_GetAllAds(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIServer_GetAllAdsCursor(t *testing.T) {
+	tests := []struct {
+		server            APIServer
+		name              string
+		url               string
+		populationSize    int
+		expectedPagesSize []int
+	}{
+		{
+			server:            APIServer{DBManager: db.NewMockedDBManager()},
+			name:              "Walk equal prices ascending",
+			url:               "/ads?perPage=2&sortBy=price&sortDirection=asc",
+			populationSize:    5,
+			expectedPagesSize: []int{2, 2, 1},
+		},
+		{
+			server:            APIServer{DBManager: db.NewMockedDBManager()},
+			name:              "Walk equal prices descending",
+			url:               "/ads?perPage=3&sortBy=price&sortDirection=desc",
+			populationSize:    6,
+			expectedPagesSize: []int{3, 3},
+		},
+		{
+			server:            APIServer{DBManager: db.NewMockedDBManager()},
+			name:              "Walk creation date",
+			url:               "/ads?perPage=4&sortBy=createdAt",
+			populationSize:    5,
+			expectedPagesSize: []int{4, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.server.DBManager.Close()
+			categoryID := populateCategory(t, tt.server)
+			ownerID := populateUser(t, tt.server)
+			for i := 0; i < tt.populationSize; i++ {
+				request, err := newRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(withCategory(t, `{"title":"title","description":"description","photoLinks":["http://google.com"],"price":100}`, categoryID))))
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr := httptest.NewRecorder()
+				tt.server.NewAd(rr, asUser(request, ownerID))
+				time.Sleep(time.Nanosecond) // without sleep insertions are too fast
+			}
+			seen := map[string]bool{}
+			url := strings.ToLower(tt.url)
+			for page, expectedSize := range tt.expectedPagesSize {
+				request, err := newRequest(http.MethodGet, url, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr := httptest.NewRecorder()
+				tt.server.GetAllAds(rr, request)
+				assert.Equal(t, http.StatusOK, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, http.StatusOK))
+				var tmpData []map[string]interface{}
+				_ = json.Unmarshal(rr.Body.Bytes(), &tmpData)
+				assert.Equal(t, expectedSize, len(tmpData), fmt.Sprintf("wrong length of page %v: got %v expected %v", page+1, len(tmpData), expectedSize))
+				for _, ad := range tmpData {
+					adID := ad["adID"].(string)
+					assert.False(t, seen[adID], fmt.Sprintf("ad %v returned twice", adID))
+					seen[adID] = true
+				}
+				if page > 0 {
+					assert.Empty(t, rr.Header().Get("X-Total-Count"), "cursor page was counted")
+				}
+				nextCursor := rr.Header().Get("X-Next-Cursor")
+				if page == len(tt.expectedPagesSize)-1 {
+					assert.Empty(t, nextCursor, "cursor returned for the last page")
+				} else if nextCursor == "" {
+					t.Fatalf("no cursor returned for page %v", page+1)
+				}
+				url = strings.ToLower(tt.url) + "&cursor=" + nextCursor
+			}
+			assert.Equal(t, tt.populationSize, len(seen), "not all ads were returned")
+		})
+	}
+}
+
+func TestAPIServer_GetAllAdsBadCursor(t *testing.T) {
+	server := APIServer{DBManager: db.NewMockedDBManager()}
+	defer server.DBManager.Close()
+	lastAd := &models.DbAd{AdID: "22e88a53-3c80-429d-9e84-99d217788098", Price: 100}
+	for _, url := range []string{
+		"/ads?cursor=abc",
+		"/ads?cursor=" + encodeAdsCursor("price", "asc", lastAd),
+		"/ads?sortby=price&sortdirection=desc&cursor=" + encodeAdsCursor("price", "asc", lastAd),
+	} {
+		request, err := newRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		server.GetAllAds(rr, request)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, fmt.Sprintf("unexpected http code for %v: got %v expected %v", url, rr.Code, http.StatusBadRequest))
+		assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+		var problem models.Problem
+		err = json.Unmarshal(rr.Body.Bytes(), &problem)
+		if err != nil {
+			t.Fatalf("unexpected output: %v", err)
+		}
+		assert.Equal(t, models.Problem{Type: "about:blank", Title: "Bad Request", Status: http.StatusBadRequest, Code: "invalid_parameter", Detail: problem.Detail}, problem)
+		assert.NotEmpty(t, problem.Detail)
+	}
+}
+
+func int64Pointer(value int64) *int64 {
+	return &value
+}
+
+func TestAPIServer_GetAllAdsPagination(t *testing.T) {
+	tests := []struct {
+		server              APIServer
+		name                string
+		url                 string
+		populationSize      int
+		expectedBody        string
+		expectedTotalHeader string
+		expectedLinkHeader  string
+		expectedNextCursor  bool
+		expectedEnvelope    *models.AdsPage
+	}{
+		{
+			server:              APIServer{DBManager: db.NewMockedDBManager()},
+			name:                "Empty list is an array",
+			url:                 "/ads",
+			populationSize:      0,
+			expectedBody:        "[]\n",
+			expectedTotalHeader: "0",
+			expectedLinkHeader:  `</ads?page=1&perpage=10>; rel="first", </ads?page=1&perpage=10>; rel="last"`,
+		},
+		{
+			server:              APIServer{DBManager: db.NewMockedDBManager()},
+			name:                "Headers of middle page",
+			url:                 "/ads?page=2&perPage=2",
+			populationSize:      5,
+			expectedTotalHeader: "5",
+			expectedLinkHeader:  `</ads?page=1&perpage=2>; rel="first", </ads?page=1&perpage=2>; rel="prev", </ads?page=3&perpage=2>; rel="next", </ads?page=3&perpage=2>; rel="last"`,
+			expectedNextCursor:  true,
+		},
+		{
+			server:              APIServer{DBManager: db.NewMockedDBManager()},
+			name:                "Full last page has no cursor",
+			url:                 "/ads?page=2&perPage=2",
+			populationSize:      4,
+			expectedTotalHeader: "4",
+			expectedLinkHeader:  `</ads?page=1&perpage=2>; rel="first", </ads?page=1&perpage=2>; rel="prev", </ads?page=2&perpage=2>; rel="last"`,
+		},
+		{
+			server:              APIServer{DBManager: db.NewMockedDBManager()},
+			name:                "Zero page is the first one",
+			url:                 "/ads?page=0&perPage=2",
+			populationSize:      3,
+			expectedTotalHeader: "3",
+			expectedLinkHeader:  `</ads?page=1&perpage=2>; rel="first", </ads?page=2&perpage=2>; rel="next", </ads?page=2&perpage=2>; rel="last"`,
+			expectedNextCursor:  true,
+		},
+		{
+			server:           APIServer{DBManager: db.NewMockedDBManager()},
+			name:             "Envelope of negative page",
+			url:              "/ads?page=-3&perPage=2&envelope=true",
+			populationSize:   3,
+			expectedEnvelope: &models.AdsPage{Total: int64Pointer(3), Page: 1, PerPage: 2, Pages: int64Pointer(2)},
+		},
+		{
+			server:           APIServer{DBManager: db.NewMockedDBManager()},
+			name:             "Envelope of last page",
+			url:              "/ads?page=3&perPage=2&envelope=true",
+			populationSize:   5,
+			expectedEnvelope: &models.AdsPage{Total: int64Pointer(5), Page: 3, PerPage: 2, Pages: int64Pointer(3)},
+		},
+		{
+			server:           APIServer{DBManager: db.NewMockedDBManager()},
+			name:             "Envelope honours filters",
+			url:              "/ads?perPage=2&envelope=true&minPrice=1000",
+			populationSize:   5,
+			expectedEnvelope: &models.AdsPage{Total: int64Pointer(0), Page: 1, PerPage: 2, Pages: int64Pointer(0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.server.DBManager.Close()
+			categoryID := populateCategory(t, tt.server)
+			ownerID := populateUser(t, tt.server)
+			for i := 0; i < tt.populationSize; i++ {
+				request, err := newRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(withCategory(t, `{"title":"title","description":"description","photoLinks":["http://google.com"],"price":100}`, categoryID))))
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr := httptest.NewRecorder()
+				tt.server.NewAd(rr, asUser(request, ownerID))
+			}
+			request, err := newRequest(http.MethodGet, strings.ToLower(tt.url), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			tt.server.GetAllAds(rr, request)
+			assert.Equal(t, http.StatusOK, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, http.StatusOK))
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rr.Body.String())
+			}
+			if tt.expectedEnvelope != nil {
+				var envelope models.AdsPage
+				err = json.Unmarshal(rr.Body.Bytes(), &envelope)
+				if err != nil {
+					t.Fatalf("unexpected output: %v", err)
+				}
+				expectedItems := int(*tt.expectedEnvelope.Total) - (tt.expectedEnvelope.Page-1)*tt.expectedEnvelope.PerPage
+				if expectedItems > tt.expectedEnvelope.PerPage {
+					expectedItems = tt.expectedEnvelope.PerPage
+				}
+				assert.Equal(t, expectedItems, len(envelope.Items), "wrong items length")
+				envelope.Items = nil
+				envelope.NextCursor = ""
+				assert.Equal(t, *tt.expectedEnvelope, envelope)
+				assert.Empty(t, rr.Header().Get("X-Total-Count"), "envelope response has pagination headers")
+			} else {
+				assert.Equal(t, tt.expectedTotalHeader, rr.Header().Get("X-Total-Count"))
+				assert.Equal(t, tt.expectedLinkHeader, rr.Header().Get("Link"))
+				assert.Equal(t, tt.expectedNextCursor, rr.Header().Get("X-Next-Cursor") != "")
+			}
+		})
+	}
+}
+
+func TestAPIServer_GetAllAdsByCategory(t *testing.T) {
+	server := APIServer{DBManager: db.NewMockedDBManager()}
+	defer server.DBManager.Close()
+	electronics := populateCategory(t, server)
+	phones, err := server.DBManager.NewCategory(context.Background(), models.CreatingCategory{Name: "phones", ParentID: &electronics})
+	if err != nil {
+		t.Fatal(err)
+	}
+	smartphones, err := server.DBManager.NewCategory(context.Background(), models.CreatingCategory{Name: "smartphones", ParentID: &phones})
+	if err != nil {
+		t.Fatal(err)
+	}
+	furniture := populateCategory(t, server)
+	ownerID := populateUser(t, server)
+	population := map[string]string{
+		electronics: `{"title":"tv","description":"description","photoLinks":["http://google.com"],"price":100}`,
+		phones:      `{"title":"landline","description":"description","photoLinks":["http://google.com"],"price":100}`,
+		smartphones: `{"title":"smartphone","description":"description","photoLinks":["http://google.com"],"price":100}`,
+		furniture:   `{"title":"chair","description":"description","photoLinks":["http://google.com"],"price":100}`,
+	}
+	for categoryID, insertData := range population {
+		request, err := newRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(withCategory(t, insertData, categoryID))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		server.NewAd(rr, asUser(request, ownerID))
+		assert.Equal(t, http.StatusOK, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, http.StatusOK))
+	}
+	tests := []struct {
+		name           string
+		categoryID     string
+		expectedTitles []string
+	}{
+		{name: "Root category includes descendants", categoryID: electronics, expectedTitles: []string{"landline", "smartphone", "tv"}},
+		{name: "Middle category includes descendants", categoryID: phones, expectedTitles: []string{"landline", "smartphone"}},
+		{name: "Leaf category", categoryID: smartphones, expectedTitles: []string{"smartphone"}},
+		{name: "Unknown category", categoryID: "22e88a53-3c80-429d-9e84-99d217788098", expectedTitles: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := newRequest(http.MethodGet, "/ads?category="+tt.categoryID, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			server.GetAllAds(rr, request)
+			var tmpData []map[string]interface{}
+			err = json.Unmarshal(rr.Body.Bytes(), &tmpData)
+			if err != nil {
+				t.Fatalf("unexpected output: %v", err)
+			}
+			titles := []string{}
+			for _, ad := range tmpData {
+				titles = append(titles, ad["title"].(string))
+			}
+			assert.ElementsMatch(t, tt.expectedTitles, titles)
+		})
+	}
+}
+
+func TestAPIServer_GetAllAdsTimeout(t *testing.T) {
+	tests := []struct {
+		name                string
+		cancel              bool
+		expectedOutputCode  int
+		expectedProblemCode string
+	}{
+		{
+			name:                "Deadline exceeded",
+			expectedOutputCode:  http.StatusGatewayTimeout,
+			expectedProblemCode: "database_timeout",
+		},
+		{
+			name:                "Request canceled",
+			cancel:              true,
+			expectedOutputCode:  http.StatusServiceUnavailable,
+			expectedProblemCode: "request_canceled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbManager := db.NewMockedDBManager()
+			dbManager.SetLatency(time.Second)
+			server := APIServer{DBManager: dbManager}
+			defer server.DBManager.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+			request, err := http.NewRequestWithContext(ctx, http.MethodGet, "/ads", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			server.GetAllAds(rr, request)
+			assert.Equal(t, tt.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode))
+			var problem models.Problem
+			err = json.Unmarshal(rr.Body.Bytes(), &problem)
+			if err != nil {
+				t.Fatalf("unexpected output: %v", err)
+			}
+			assert.Equal(t, tt.expectedProblemCode, problem.Code)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"net/http"
+
+	"adv-backend-trainee-assignment/src/logging"
+	"adv-backend-trainee-assignment/src/problem"
+)
+
+func (server APIServer) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (server APIServer) Readyz(w http.ResponseWriter, r *http.Request) {
+	if err := server.DBManager.Ping(r.Context()); err != nil {
+		logging.FromContext(r.Context()).Errorf("db is not ready. err: [%s]", err)
+		problem.Write(w, http.StatusServiceUnavailable, problem.CodeServiceNotReady, "db is not ready")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("ok"))
+}
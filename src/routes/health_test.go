@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"adv-backend-trainee-assignment/src/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIServer_Healthz(t *testing.T) {
+	server := APIServer{DBManager: db.NewMockedDBManager()}
+	request, err := newRequest(http.MethodGet, "/healthz", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	server.Healthz(rr, request)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestAPIServer_Readyz(t *testing.T) {
+	tests := []struct {
+		name               string
+		latency            time.Duration
+		expectedOutputCode int
+	}{
+		{
+			name:               "Db is reachable",
+			expectedOutputCode: http.StatusOK,
+		},
+		{
+			name:               "Db doesn't answer in time",
+			latency:            time.Second,
+			expectedOutputCode: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbManager := db.NewMockedDBManager()
+			dbManager.SetLatency(tt.latency)
+			server := APIServer{DBManager: dbManager}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			request, err := http.NewRequestWithContext(ctx, http.MethodGet, "/readyz", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			server.Readyz(rr, request)
+			assert.Equal(t, tt.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode))
+		})
+	}
+}
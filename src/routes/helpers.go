@@ -1,17 +1,35 @@
 package routes
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"mime"
 	"net/http"
+	"strings"
+	"time"
 
+	"adv-backend-trainee-assignment/src/auth"
 	"adv-backend-trainee-assignment/src/db"
-	log "github.com/sirupsen/logrus"
+	"adv-backend-trainee-assignment/src/logging"
+	"adv-backend-trainee-assignment/src/models"
+	"adv-backend-trainee-assignment/src/problem"
+	"adv-backend-trainee-assignment/src/validation"
 )
 
+const defaultMaxBodyBytes = 1 << 20
+
 type APIServer struct {
 	DBManager db.DatabaseConnection
+	AdLimits  validation.AdLimits
+	// MaxBodyBytes limits request bodies. Zero means 1 MiB.
+	MaxBodyBytes int64
+	// MaxBatchSize limits ads in a single batch. Zero means 100.
+	MaxBatchSize int
+	// IdempotencyTTL is how long Idempotency-Key responses are kept. Zero means 24 hours.
+	IdempotencyTTL time.Duration
 }
 
 type (
@@ -20,23 +38,80 @@ type (
 		Method      string
 		Pattern     string
 		HandlerFunc http.HandlerFunc
+		// Roles allowed to call the route. Empty means any authenticated caller.
+		Roles []string
 	}
 	Routes []Route
 )
 
+var (
+	sellers    = []string{auth.RoleSeller, auth.RoleModerator, auth.RoleAdmin}
+	moderators = []string{auth.RoleModerator, auth.RoleAdmin}
+	admins     = []string{auth.RoleAdmin}
+)
+
 func GenerateRoutes(apiServer APIServer) Routes {
 	return Routes{
 		Route{
-			"create ad",
-			"POST",
-			"/ad",
-			apiServer.NewAd,
+			Name:        "create ad",
+			Method:      "POST",
+			Pattern:     "/ad",
+			HandlerFunc: apiServer.withIdempotency(apiServer.NewAd),
+			Roles:       sellers,
+		},
+		Route{
+			Name:        "create ads",
+			Method:      "POST",
+			Pattern:     "/ads:batch",
+			HandlerFunc: apiServer.NewAds,
+			Roles:       admins,
+		},
+		Route{
+			Name:        "search ads",
+			Method:      "GET",
+			Pattern:     "/ads/search",
+			HandlerFunc: apiServer.SearchAds,
+		},
+		Route{
+			Name:        "get ad",
+			Method:      "GET",
+			Pattern:     "/ads/{adID}",
+			HandlerFunc: apiServer.SelectAd,
+		},
+		Route{
+			Name:        "update ad",
+			Method:      "PATCH",
+			Pattern:     "/ads/{adID}",
+			HandlerFunc: apiServer.UpdateAd,
+			Roles:       sellers,
+		},
+		Route{
+			Name:        "replace ad",
+			Method:      "PUT",
+			Pattern:     "/ads/{adID}",
+			HandlerFunc: apiServer.UpdateAd,
+			Roles:       sellers,
 		},
 		Route{
-			"get ad",
-			"GET",
-			"/ads/{adID}",
-			apiServer.SelectAd,
+			Name:        "delete ad",
+			Method:      "DELETE",
+			Pattern:     "/ads/{adID}",
+			HandlerFunc: apiServer.DeleteAd,
+			Roles:       sellers,
+		},
+		Route{
+			Name:        "restore ad",
+			Method:      "POST",
+			Pattern:     "/admin/ads/{adID}/restore",
+			HandlerFunc: apiServer.RestoreAd,
+			Roles:       moderators,
+		},
+		Route{
+			Name:        "purge ads",
+			Method:      "POST",
+			Pattern:     "/admin/ads/purge",
+			HandlerFunc: apiServer.PurgeDeletedAds,
+			Roles:       admins,
 		},
 		Route{
 			Name:        "get ads",
@@ -44,19 +119,166 @@ func GenerateRoutes(apiServer APIServer) Routes {
 			Pattern:     "/ads",
 			HandlerFunc: apiServer.GetAllAds,
 		},
+		Route{
+			Name:        "create user",
+			Method:      "POST",
+			Pattern:     "/users",
+			HandlerFunc: apiServer.NewUser,
+			Roles:       admins,
+		},
+		Route{
+			Name:        "get user",
+			Method:      "GET",
+			Pattern:     "/users/{userID}",
+			HandlerFunc: apiServer.SelectUser,
+		},
+		Route{
+			Name:        "get user ads",
+			Method:      "GET",
+			Pattern:     "/users/{userID}/ads",
+			HandlerFunc: apiServer.GetUserAds,
+		},
+		Route{
+			Name:        "create category",
+			Method:      "POST",
+			Pattern:     "/categories",
+			HandlerFunc: apiServer.NewCategory,
+			Roles:       admins,
+		},
+		Route{
+			Name:        "get categories",
+			Method:      "GET",
+			Pattern:     "/categories",
+			HandlerFunc: apiServer.GetAllCategories,
+		},
+		Route{
+			Name:        "get category",
+			Method:      "GET",
+			Pattern:     "/categories/{categoryID}",
+			HandlerFunc: apiServer.SelectCategory,
+		},
+		Route{
+			Name:        "update category",
+			Method:      "PUT",
+			Pattern:     "/categories/{categoryID}",
+			HandlerFunc: apiServer.UpdateCategory,
+			Roles:       admins,
+		},
+		Route{
+			Name:        "delete category",
+			Method:      "DELETE",
+			Pattern:     "/categories/{categoryID}",
+			HandlerFunc: apiServer.DeleteCategory,
+			Roles:       admins,
+		},
 	}
 }
 
-func (server APIServer) parseRequest(r *http.Request, parseStruct interface{}) error {
-	body, err := ioutil.ReadAll(r.Body)
+// isBodyTooLarge reports the error of http.MaxBytesReader, which isn't exported before go 1.19.
+func isBodyTooLarge(err error) bool {
+	return err != nil && err.Error() == "http: request body too large"
+}
+
+// parseRequest decodes a single JSON object from a body of at most MaxBodyBytes into parseStruct.
+// It writes the error response itself and returns false when the body is rejected.
+func (server APIServer) parseRequest(w http.ResponseWriter, r *http.Request, parseStruct interface{}) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		problem.Write(w, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "content type should be application/json")
+		return false
+	}
+	maxBodyBytes := server.MaxBodyBytes
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = defaultMaxBodyBytes
+	}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(parseStruct)
+	if err == nil {
+		err = decoder.Decode(&struct{}{})
+		if err == io.EOF {
+			return true
+		} else if isBodyTooLarge(err) {
+			problem.Write(w, http.StatusRequestEntityTooLarge, problem.CodeBodyTooLarge, fmt.Sprintf("body should be at most %d bytes", maxBodyBytes))
+		} else {
+			problem.Write(w, http.StatusBadRequest, problem.CodeInvalidBody, "unexpected data after JSON body")
+		}
+		return false
+	}
+	logging.FromContext(r.Context()).Warnf("couldn't parse body. err: [%s]", err)
+	if isBodyTooLarge(err) {
+		problem.Write(w, http.StatusRequestEntityTooLarge, problem.CodeBodyTooLarge, fmt.Sprintf("body should be at most %d bytes", maxBodyBytes))
+	} else if strings.HasPrefix(err.Error(), "json: unknown field ") {
+		problem.Write(w, http.StatusBadRequest, problem.CodeInvalidBody, "unknown field "+strings.TrimPrefix(err.Error(), "json: unknown field "))
+	} else {
+		problem.Write(w, http.StatusBadRequest, problem.CodeInvalidBody, "can't parse body to struct")
+	}
+	return false
+}
+
+func (server APIServer) checkCategory(w http.ResponseWriter, r *http.Request, categoryID string) bool {
+	category, err := server.DBManager.SelectCategory(r.Context(), categoryID)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("couldn't get category with id %s from db. err: [%s]", categoryID, err)
+		writeDBError(w, err, "error getting category from db")
+		return false
+	} else if category == nil {
+		problem.Write(w, http.StatusBadRequest, problem.CodeUnknownCategory, "unknown category", models.FieldError{Field: "categoryID", Code: validation.CodeInvalid, Message: "category doesn't exist"})
+		return false
+	}
+	return true
+}
+
+func (server APIServer) authorizeUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	identity, ok := auth.FromContext(r.Context())
+	if !ok || identity.UserID == "" {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "missing user identity")
+		return "", false
+	}
+	userID := identity.UserID
+	user, err := server.DBManager.SelectUser(r.Context(), userID)
 	if err != nil {
-		log.Printf("Error reading body: %v", err)
-		return fmt.Errorf("can't read body")
+		logging.FromContext(r.Context()).Errorf("couldn't get user with id %s from db. err: [%s]", userID, err)
+		writeDBError(w, err, "error getting user from db")
+		return "", false
+	} else if user == nil {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "unknown user")
+		return "", false
+	}
+	return userID, true
+}
+
+func (server APIServer) authorizeAdOwner(w http.ResponseWriter, r *http.Request, adID string) bool {
+	var userID string
+	identity, _ := auth.FromContext(r.Context())
+	moderator := identity.HasRole(auth.RoleModerator, auth.RoleAdmin)
+	if !moderator {
+		var ok bool
+		if userID, ok = server.authorizeUser(w, r); !ok {
+			return false
+		}
 	}
-	err = json.Unmarshal(body, &parseStruct)
+	ad, err := server.DBManager.SelectAd(r.Context(), adID)
 	if err != nil {
-		log.Error(err)
-		return fmt.Errorf("can't parse body to struct")
+		logging.FromContext(r.Context()).Errorf("couldn't get ad with id %s from db. err: [%s]", adID, err)
+		writeDBError(w, err, "error getting ad from db")
+		return false
+	} else if ad == nil {
+		problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "ad not found")
+		return false
+	} else if !moderator && ad.OwnerID != userID {
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "ad belongs to another user")
+		return false
+	}
+	return true
+}
+
+func writeDBError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		problem.Write(w, http.StatusGatewayTimeout, problem.CodeDatabaseTimeout, "database timeout")
+	} else if errors.Is(err, context.Canceled) {
+		problem.Write(w, http.StatusServiceUnavailable, problem.CodeRequestCanceled, "request canceled")
+	} else {
+		problem.Write(w, http.StatusInternalServerError, problem.CodeInternal, message)
 	}
-	return nil
 }
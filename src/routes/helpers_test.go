@@ -0,0 +1,173 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"adv-backend-trainee-assignment/src/auth"
+	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRequest is http.NewRequest that marks a non-nil body as JSON.
+func newRequest(method, url string, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequest(method, url, body)
+	if err == nil && body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	return request, err
+}
+
+func populateCategory(t *testing.T, server APIServer) string {
+	categoryID, err := server.DBManager.NewCategory(context.Background(), models.CreatingCategory{Name: "category"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return categoryID
+}
+
+func withCategory(t *testing.T, body string, categoryID string) string {
+	var tmpData map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewBufferString(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tmpData); err != nil {
+		return body
+	}
+	if _, ok := tmpData["categoryID"]; !ok {
+		tmpData["categoryID"] = categoryID
+	}
+	res, err := json.Marshal(tmpData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(res)
+}
+
+func populateUser(t *testing.T, server APIServer) string {
+	userID, err := server.DBManager.NewUser(context.Background(), models.CreatingUser{Name: "seller", Email: uuid.New().String() + "@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userID
+}
+
+func asUser(request *http.Request, userID string, roles ...string) *http.Request {
+	return request.WithContext(auth.NewContext(request.Context(), auth.NewIdentity(userID, roles)))
+}
+
+func TestGenerateRoutes_AdminRoutesRestricted(t *testing.T) {
+	for _, route := range GenerateRoutes(APIServer{DBManager: db.NewMockedDBManager()}) {
+		if strings.HasPrefix(route.Pattern, "/admin/") || route.Name == "create user" || route.Pattern == "/ads:batch" {
+			assert.NotContains(t, route.Roles, auth.RoleSeller, route.Name)
+			assert.NotEmpty(t, route.Roles, route.Name)
+		}
+	}
+}
+
+func TestAPIServer_ParseRequest(t *testing.T) {
+	tests := []struct {
+		name                string
+		server              APIServer
+		contentType         string
+		body                string
+		expectedOutputCode  int
+		expectedProblemCode string
+	}{
+		{
+			name:               "Valid body",
+			contentType:        "application/json",
+			body:               `{"name":"category"}`,
+			expectedOutputCode: http.StatusOK,
+		},
+		{
+			name:               "Content type with charset",
+			contentType:        "application/json; charset=utf-8",
+			body:               `{"name":"category"}`,
+			expectedOutputCode: http.StatusOK,
+		},
+		{
+			name:                "Missing content type",
+			body:                `{"name":"category"}`,
+			expectedOutputCode:  http.StatusUnsupportedMediaType,
+			expectedProblemCode: "unsupported_media_type",
+		},
+		{
+			name:                "Form content type",
+			contentType:         "application/x-www-form-urlencoded",
+			body:                `name=category`,
+			expectedOutputCode:  http.StatusUnsupportedMediaType,
+			expectedProblemCode: "unsupported_media_type",
+		},
+		{
+			name:                "Body over the limit",
+			server:              APIServer{MaxBodyBytes: 16},
+			contentType:         "application/json",
+			body:                `{"name":"` + strings.Repeat("a", 32) + `"}`,
+			expectedOutputCode:  http.StatusRequestEntityTooLarge,
+			expectedProblemCode: "body_too_large",
+		},
+		{
+			name:                "Unknown field",
+			contentType:         "application/json",
+			body:                `{"name":"category","color":"red"}`,
+			expectedOutputCode:  http.StatusBadRequest,
+			expectedProblemCode: "invalid_body",
+		},
+		{
+			name:                "Trailing data",
+			contentType:         "application/json",
+			body:                `{"name":"category"}{"name":"other"}`,
+			expectedOutputCode:  http.StatusBadRequest,
+			expectedProblemCode: "invalid_body",
+		},
+		{
+			name:                "Trailing data over the limit",
+			server:              APIServer{MaxBodyBytes: 30},
+			contentType:         "application/json",
+			body:                `{"name":"category"}{"name":"other"}`,
+			expectedOutputCode:  http.StatusRequestEntityTooLarge,
+			expectedProblemCode: "body_too_large",
+		},
+		{
+			name:                "Broken JSON",
+			contentType:         "application/json",
+			body:                `{"name":`,
+			expectedOutputCode:  http.StatusBadRequest,
+			expectedProblemCode: "invalid_body",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodPost, "/categories", bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			rr := httptest.NewRecorder()
+			var category models.CreatingCategory
+			if tt.server.parseRequest(rr, request, &category) {
+				assert.Equal(t, "category", category.Name)
+			}
+			assert.Equal(t, tt.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode))
+			if tt.expectedProblemCode != "" {
+				var problem models.Problem
+				err = json.Unmarshal(rr.Body.Bytes(), &problem)
+				if err != nil {
+					t.Fatalf("unexpected output: %v", err)
+				}
+				assert.Equal(t, tt.expectedProblemCode, problem.Code)
+			}
+		})
+	}
+}
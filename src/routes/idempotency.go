@@ -0,0 +1,122 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"adv-backend-trainee-assignment/src/auth"
+	"adv-backend-trainee-assignment/src/logging"
+	"adv-backend-trainee-assignment/src/problem"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	defaultIdempotencyTTL     = 24 * time.Hour
+	maxIdempotencyKeyLength   = 255
+	idempotencyCompletionWait = 5 * time.Second
+)
+
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (recorder *idempotencyRecorder) WriteHeader(status int) {
+	if recorder.status == 0 {
+		recorder.status = status
+	}
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *idempotencyRecorder) Write(data []byte) (int, error) {
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+	recorder.body.Write(data)
+	return recorder.ResponseWriter.Write(data)
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	_, _ = io.WriteString(hash, r.Method+" "+r.URL.Path+"\n")
+	_, _ = hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// withIdempotency replays the stored response when a request is retried with the same Idempotency-Key.
+// Keys are scoped to the caller, and only successful responses are stored, so failed requests can be retried.
+func (server APIServer) withIdempotency(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		identity, ok := auth.FromContext(r.Context())
+		if key == "" || !ok || identity.UserID == "" {
+			next(w, r)
+			return
+		}
+		// keys reference their owner, so an unknown caller is rejected as it would be without a key
+		ownerID, ok := server.authorizeUser(w, r)
+		if !ok {
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			problem.Write(w, http.StatusBadRequest, problem.CodeInvalidParameter, IdempotencyKeyHeader+" should be at most "+strconv.Itoa(maxIdempotencyKeyLength)+" characters long")
+			return
+		}
+		maxBodyBytes := server.MaxBodyBytes
+		if maxBodyBytes <= 0 {
+			maxBodyBytes = defaultMaxBodyBytes
+		}
+		// one byte over the limit is enough for parseRequest to reject the body
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+		if err != nil {
+			problem.Write(w, http.StatusBadRequest, problem.CodeInvalidBody, "can't read body")
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		hash := requestHash(r, body)
+		ttl := server.IdempotencyTTL
+		if ttl <= 0 {
+			ttl = defaultIdempotencyTTL
+		}
+		record, err := server.DBManager.ReserveIdempotencyKey(r.Context(), ownerID, key, hash, time.Now().UTC().Add(ttl))
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("couldn't reserve idempotency key %s. err: [%s]", key, err)
+			writeDBError(w, err, "error reserving idempotency key in db")
+			return
+		} else if record != nil {
+			if record.RequestHash != hash {
+				problem.Write(w, http.StatusUnprocessableEntity, problem.CodeIdempotencyKeyReused, IdempotencyKeyHeader+" was already used with a different request")
+			} else if record.ResponseCode == 0 {
+				problem.Write(w, http.StatusConflict, problem.CodeIdempotencyKeyInProgress, "request with this "+IdempotencyKeyHeader+" is still in progress")
+			} else {
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.Header().Set(idempotentReplayedHeader, "true")
+				w.WriteHeader(record.ResponseCode)
+				_, _ = w.Write(record.ResponseBody)
+			}
+			return
+		}
+		recorder := &idempotencyRecorder{ResponseWriter: w}
+		next(recorder, r)
+		// the response is already sent, so bookkeeping shouldn't depend on the client staying connected
+		ctx, cancel := context.WithTimeout(context.Background(), idempotencyCompletionWait)
+		defer cancel()
+		if recorder.status >= 200 && recorder.status < 300 {
+			err = server.DBManager.CompleteIdempotencyKey(ctx, ownerID, key, recorder.status, recorder.body.Bytes())
+		} else {
+			err = server.DBManager.ReleaseIdempotencyKey(ctx, ownerID, key)
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("couldn't finish idempotency key %s. err: [%s]", key, err)
+		}
+	}
+}
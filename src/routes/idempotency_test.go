@@ -0,0 +1,154 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIServer_NewAdIdempotency(t *testing.T) {
+	const validAd = `{"title":"title","description":"description","photoLinks":["https://ya.ru"],"price":100}`
+	tests := []struct {
+		name                string
+		key                 string
+		bodies              []string
+		anotherOwner        bool
+		pending             bool
+		expectedOutputCodes []int
+		expectedSameAd      bool
+		expectedAdsCount    int
+	}{
+		{
+			name:                "Retry without key",
+			bodies:              []string{validAd, validAd},
+			expectedOutputCodes: []int{http.StatusOK, http.StatusOK},
+			expectedAdsCount:    2,
+		},
+		{
+			name:                "Retry with key",
+			key:                 "retry-1",
+			bodies:              []string{validAd, validAd, validAd},
+			expectedOutputCodes: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+			expectedSameAd:      true,
+			expectedAdsCount:    1,
+		},
+		{
+			name:                "Key reused with different body",
+			key:                 "retry-1",
+			bodies:              []string{validAd, `{"title":"other","description":"description","photoLinks":["https://ya.ru"],"price":100}`},
+			expectedOutputCodes: []int{http.StatusOK, http.StatusUnprocessableEntity},
+			expectedAdsCount:    1,
+		},
+		{
+			name:                "Key of request in progress",
+			key:                 "retry-1",
+			bodies:              []string{validAd},
+			pending:             true,
+			expectedOutputCodes: []int{http.StatusConflict},
+			expectedAdsCount:    0,
+		},
+		{
+			name:                "Retry after failed request",
+			key:                 "retry-1",
+			bodies:              []string{`{"title":"","description":"description","photoLinks":["https://ya.ru"],"price":100}`, validAd},
+			expectedOutputCodes: []int{http.StatusBadRequest, http.StatusOK},
+			expectedAdsCount:    1,
+		},
+		{
+			name:                "Same key of another owner",
+			key:                 "retry-1",
+			bodies:              []string{validAd, validAd},
+			anotherOwner:        true,
+			expectedOutputCodes: []int{http.StatusOK, http.StatusOK},
+			expectedAdsCount:    2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := APIServer{DBManager: db.NewMockedDBManager()}
+			defer server.DBManager.Close()
+			handler := server.withIdempotency(server.NewAd)
+			categoryID := populateCategory(t, server)
+			ownerIDs := []string{populateUser(t, server)}
+			if tt.anotherOwner {
+				ownerIDs = append(ownerIDs, populateUser(t, server))
+			}
+			if tt.pending {
+				body := withCategory(t, tt.bodies[0], categoryID)
+				request, err := newRequest(http.MethodPost, "/ad", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, err = server.DBManager.ReserveIdempotencyKey(context.Background(), ownerIDs[0], tt.key, requestHash(request, []byte(body)), time.Now().Add(time.Hour))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			var adIDs []string
+			for i, body := range tt.bodies {
+				request, err := newRequest(http.MethodPost, "/ad", bytes.NewBufferString(withCategory(t, body, categoryID)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.key != "" {
+					request.Header.Set(IdempotencyKeyHeader, tt.key)
+				}
+				rr := httptest.NewRecorder()
+				handler(rr, asUser(request, ownerIDs[i%len(ownerIDs)]))
+				assert.Equal(t, tt.expectedOutputCodes[i], rr.Code, fmt.Sprintf("unexpected http code of request %d: got %v expected %v", i, rr.Code, tt.expectedOutputCodes[i]))
+				if rr.Code == http.StatusOK {
+					var createdAd models.CreatedAd
+					err = json.Unmarshal(rr.Body.Bytes(), &createdAd)
+					if err != nil {
+						t.Fatalf("unexpected output: %v", err)
+					}
+					adIDs = append(adIDs, createdAd.AdID)
+					assert.Equal(t, tt.expectedSameAd && len(adIDs) > 1, rr.Header().Get("Idempotent-Replayed") == "true")
+				}
+			}
+			if tt.expectedSameAd {
+				for _, adID := range adIDs {
+					assert.Equal(t, adIDs[0], adID)
+				}
+			}
+			count, err := server.DBManager.CountAds(context.Background(), models.AdsFilter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, int64(tt.expectedAdsCount), count)
+		})
+	}
+}
+
+func TestAPIServer_NewAdIdempotencyUnknownUser(t *testing.T) {
+	manager, err := db.NewBoltManager(filepath.Join(t.TempDir(), "ads.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := APIServer{DBManager: manager}
+	defer server.DBManager.Close()
+	handler := server.withIdempotency(server.NewAd)
+	for _, key := range []string{"", "retry-1"} {
+		request, err := newRequest(http.MethodPost, "/ad", bytes.NewBufferString(`{"title":"title","description":"description","photoLinks":["https://ya.ru"],"price":100}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != "" {
+			request.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, asUser(request, "unregistered"))
+		assert.Equal(t, http.StatusUnauthorized, rr.Code, "key %q", key)
+		assert.Contains(t, rr.Body.String(), "unknown user")
+	}
+}
@@ -2,28 +2,31 @@ package routes
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 
+	"adv-backend-trainee-assignment/src/logging"
 	"adv-backend-trainee-assignment/src/models"
+	"adv-backend-trainee-assignment/src/problem"
 )
 
 func (server APIServer) NewAd(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := server.authorizeUser(w, r)
+	if !ok {
+		return
+	}
 	var adData models.CreatingAd
-	err := server.parseRequest(r, &adData)
-	if err == nil {
-		if 1 <= len(adData.Title) && len(adData.Title) <= 200 && 1 <= len(adData.Description) && len(adData.Description) <= 1000 && 1 <= len(adData.PhotoLinks) && len(adData.PhotoLinks) <= 3 && 1 <= adData.Price {
-			adId, err := server.DBManager.NewAd(adData)
+	if server.parseRequest(w, r, &adData) {
+		if fieldErrors := server.AdLimits.ValidateAd(adData); len(fieldErrors) > 0 {
+			problem.Write(w, http.StatusBadRequest, problem.CodeValidationFailed, "exceeding data limitations", fieldErrors...)
+		} else if server.checkCategory(w, r, adData.CategoryID) {
+			adId, err := server.DBManager.NewAd(r.Context(), adData, ownerID)
 			if err != nil {
-				http.Error(w, "error creating ad in db", http.StatusInternalServerError)
+				logging.FromContext(r.Context()).Errorf("couldn't create ad in db. err: [%s]", err)
+				writeDBError(w, err, "error creating ad in db")
 			} else {
 				w.Header().Set("Content-Type", "application/json; charset=utf-8")
 				_ = json.NewEncoder(w).Encode(models.CreatedAd{AdID: adId})
 			}
-		} else {
-			http.Error(w, "exceeding data limitations", http.StatusBadRequest)
 		}
-	} else {
-		http.Error(w, fmt.Sprintf("%s", err), http.StatusBadRequest)
 	}
 }
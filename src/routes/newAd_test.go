@@ -7,9 +7,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"regexp"
+	"strings"
 	"testing"
 
 	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/models"
+	"adv-backend-trainee-assignment/src/validation"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -20,124 +23,193 @@ func TestAPIServer_NewAd(t *testing.T) {
 		body                   string
 		expectedOutputCode     int
 		expectedOutputEncoding string
+		expectedErrorFields    []string
 	}{
 		{
-			server:                 APIServer{db.NewMockedDBManager()},
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
 			name:                   "Correct insert",
 			body:                   `{"title":"title","description":"description","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":50658783}`,
 			expectedOutputCode:     http.StatusOK,
 			expectedOutputEncoding: "application/json; charset=utf-8",
 		},
 		{
-			server:                 APIServer{db.NewMockedDBManager()},
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
 			name:                   "Too small title",
 			body:                   `{"title":"","description":"desription","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":50658783}`,
 			expectedOutputCode:     http.StatusBadRequest,
-			expectedOutputEncoding: "text/plain; charset=utf-8",
+			expectedOutputEncoding: "application/problem+json",
+			expectedErrorFields:    []string{"title"},
 		},
 		{
-			server:                 APIServer{db.NewMockedDBManager()},
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
 			name:                   "Too big title",
 			body:                   `{"title":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","description":"n","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":50658783}`,
 			expectedOutputCode:     http.StatusBadRequest,
-			expectedOutputEncoding: "text/plain; charset=utf-8",
+			expectedOutputEncoding: "application/problem+json",
+			expectedErrorFields:    []string{"title"},
 		},
 		{
-			server:                 APIServer{db.NewMockedDBManager()},
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
 			name:                   "Too small description",
 			body:                   `{"title":"title","description":"","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":50658783}`,
 			expectedOutputCode:     http.StatusBadRequest,
-			expectedOutputEncoding: "text/plain; charset=utf-8",
+			expectedOutputEncoding: "application/problem+json",
+			expectedErrorFields:    []string{"description"},
 		},
 		{
-			server:                 APIServer{db.NewMockedDBManager()},
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
 			name:                   "Too big description",
 			body:                   `{"title":"title","description":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":50658783}`,
 			expectedOutputCode:     http.StatusBadRequest,
-			expectedOutputEncoding: "text/plain; charset=utf-8",
+			expectedOutputEncoding: "application/problem+json",
+			expectedErrorFields:    []string{"description"},
 		},
 		{
-			server:                 APIServer{db.NewMockedDBManager()},
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
 			name:                   "Too small price",
 			body:                   `{"title":"title","description":"description","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":0}`,
 			expectedOutputCode:     http.StatusBadRequest,
-			expectedOutputEncoding: "text/plain; charset=utf-8",
+			expectedOutputEncoding: "application/problem+json",
 		},
 		{
-			server:                 APIServer{db.NewMockedDBManager()},
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
 			name:                   "Too small price",
 			body:                   `{"title":"title","description":"description","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":-1}`,
 			expectedOutputCode:     http.StatusBadRequest,
-			expectedOutputEncoding: "text/plain; charset=utf-8",
+			expectedOutputEncoding: "application/problem+json",
 		},
 		{
-			server:                 APIServer{db.NewMockedDBManager()},
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
 			name:                   "Too small price",
 			body:                   `{"title":"title","description":"description","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":-9223372036854775809}`,
 			expectedOutputCode:     http.StatusBadRequest,
-			expectedOutputEncoding: "text/plain; charset=utf-8",
+			expectedOutputEncoding: "application/problem+json",
 		},
 		{
-			server:                 APIServer{db.NewMockedDBManager()},
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
 			name:                   "Too big price",
 			body:                   `{"title":"title","description":"description","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":9223372036854775808}`,
 			expectedOutputCode:     http.StatusBadRequest,
-			expectedOutputEncoding: "text/plain; charset=utf-8",
+			expectedOutputEncoding: "application/problem+json",
 		},
 		{
-			server:                 APIServer{db.NewMockedDBManager()},
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
 			name:                   "Empty photo links",
 			body:                   `{"title":"title","description":"description","photoLinks":[],"price":50658783}`,
 			expectedOutputCode:     http.StatusBadRequest,
-			expectedOutputEncoding: "text/plain; charset=utf-8",
+			expectedOutputEncoding: "application/problem+json",
+			expectedErrorFields:    []string{"photoLinks"},
 		},
 		{
-			server:                 APIServer{db.NewMockedDBManager()},
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
 			name:                   "Too many photo links",
 			body:                   `{"title":"title","description":"description","photoLinks":["https://ya.ru","http://google.com","https://example.com", "https://yandex.ru"],"price":50658783}`,
 			expectedOutputCode:     http.StatusBadRequest,
-			expectedOutputEncoding: "text/plain; charset=utf-8",
+			expectedOutputEncoding: "application/problem+json",
+			expectedErrorFields:    []string{"photoLinks"},
 		},
 		{
-			server:                 APIServer{db.NewMockedDBManager()},
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
+			name:                   "Cyrillic title",
+			body:                   `{"title":"` + strings.Repeat("я", 200) + `","description":"описание","photoLinks":["https://ya.ru"],"price":100}`,
+			expectedOutputCode:     http.StatusOK,
+			expectedOutputEncoding: "application/json; charset=utf-8",
+		},
+		{
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
+			name:                   "Invalid and duplicate photo links",
+			body:                   `{"title":"title","description":"description","photoLinks":["https://ya.ru","ya.ru","https://ya.ru"],"price":100}`,
+			expectedOutputCode:     http.StatusBadRequest,
+			expectedOutputEncoding: "application/problem+json",
+			expectedErrorFields:    []string{"photoLinks[1]", "photoLinks[2]"},
+		},
+		{
+			server:                 APIServer{DBManager: db.NewMockedDBManager(), AdLimits: validation.AdLimits{PriceMax: 1000}},
+			name:                   "Price above configured limit",
+			body:                   `{"title":"title","description":"description","photoLinks":["https://ya.ru"],"price":1001}`,
+			expectedOutputCode:     http.StatusBadRequest,
+			expectedOutputEncoding: "application/problem+json",
+			expectedErrorFields:    []string{"price"},
+		},
+		{
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
+			name:                   "Unknown field",
+			body:                   `{"title":"title","description":"description","photoLinks":["https://ya.ru"],"price":100,"discount":10}`,
+			expectedOutputCode:     http.StatusBadRequest,
+			expectedOutputEncoding: "application/problem+json",
+		},
+		{
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
 			name:                   "Bad JSON given",
 			body:                   `{"title":"title","description":"description","price":50658783}`,
 			expectedOutputCode:     http.StatusBadRequest,
-			expectedOutputEncoding: "text/plain; charset=utf-8",
+			expectedOutputEncoding: "application/problem+json",
 		},
 		{
-			server:                 APIServer{db.NewMockedDBManager()},
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
 			name:                   "Bad JSON given",
 			body:                   `{"title":"title","price":50658783}`,
 			expectedOutputCode:     http.StatusBadRequest,
-			expectedOutputEncoding: "text/plain; charset=utf-8",
+			expectedOutputEncoding: "application/problem+json",
 		},
 		{
-			server:                 APIServer{db.NewMockedDBManager()},
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
 			name:                   "Bad JSON given",
 			body:                   `{"title":"title"}`,
 			expectedOutputCode:     http.StatusBadRequest,
-			expectedOutputEncoding: "text/plain; charset=utf-8",
+			expectedOutputEncoding: "application/problem+json",
+			expectedErrorFields:    []string{"description", "photoLinks", "price"},
 		},
 		{
-			server:                 APIServer{db.NewMockedDBManager()},
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
 			name:                   "Bad JSON given",
 			body:                   `{}`,
 			expectedOutputCode:     http.StatusBadRequest,
-			expectedOutputEncoding: "text/plain; charset=utf-8",
+			expectedOutputEncoding: "application/problem+json",
+			expectedErrorFields:    []string{"title", "description", "photoLinks", "price"},
+		},
+		{
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
+			name:                   "Empty category",
+			body:                   `{"title":"title","description":"description","photoLinks":["https://ya.ru"],"price":50658783,"categoryID":""}`,
+			expectedOutputCode:     http.StatusBadRequest,
+			expectedOutputEncoding: "application/problem+json",
+			expectedErrorFields:    []string{"categoryID"},
+		},
+		{
+			server:                 APIServer{DBManager: db.NewMockedDBManager()},
+			name:                   "Unknown category",
+			body:                   `{"title":"title","description":"description","photoLinks":["https://ya.ru"],"price":50658783,"categoryID":"22e88a53-3c80-429d-9e84-99d217788098"}`,
+			expectedOutputCode:     http.StatusBadRequest,
+			expectedOutputEncoding: "application/problem+json",
+			expectedErrorFields:    []string{"categoryID"},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			defer tt.server.DBManager.Close()
-			request, err := http.NewRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(tt.body)))
+			ownerID := populateUser(t, tt.server)
+			request, err := newRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(withCategory(t, tt.body, populateCategory(t, tt.server)))))
 			if err != nil {
 				t.Fatal(err)
 			}
 			rr := httptest.NewRecorder()
-			tt.server.NewAd(rr, request)
+			tt.server.NewAd(rr, asUser(request, ownerID))
 			assert.Equal(t, tt.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode))
 			assert.Equal(t, tt.expectedOutputEncoding, rr.Header().Get("Content-Type"), fmt.Sprintf("unexpected http content type: got %v expected %v", rr.Header().Get("Content-Type"), tt.expectedOutputEncoding))
+			if tt.expectedErrorFields != nil {
+				var problem models.Problem
+				err = json.Unmarshal(rr.Body.Bytes(), &problem)
+				if err != nil {
+					t.Fatalf("unexpected output: %v", err)
+				}
+				fields := make([]string, 0, len(problem.Errors))
+				for _, fieldError := range problem.Errors {
+					fields = append(fields, fieldError.Field)
+				}
+				assert.Equal(t, tt.expectedOutputCode, problem.Status)
+				assert.Equal(t, tt.expectedErrorFields, fields)
+			}
 			if tt.expectedOutputCode == http.StatusOK {
 				var tmpData map[string]string
 				err = json.Unmarshal(rr.Body.Bytes(), &tmpData)
@@ -0,0 +1,87 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"adv-backend-trainee-assignment/src/logging"
+	"adv-backend-trainee-assignment/src/models"
+	"adv-backend-trainee-assignment/src/problem"
+	"adv-backend-trainee-assignment/src/validation"
+)
+
+const defaultMaxBatchSize = 100
+
+// NewAds validates every ad independently and inserts the valid ones at once.
+func (server APIServer) NewAds(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := server.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+	var batch models.CreatingAds
+	if !server.parseRequest(w, r, &batch) {
+		return
+	}
+	maxBatchSize := server.MaxBatchSize
+	if maxBatchSize <= 0 {
+		maxBatchSize = defaultMaxBatchSize
+	}
+	if len(batch.Ads) == 0 || len(batch.Ads) > maxBatchSize {
+		code := validation.CodeTooMany
+		if len(batch.Ads) == 0 {
+			code = validation.CodeTooFew
+		}
+		problem.Write(w, http.StatusBadRequest, problem.CodeValidationFailed, "exceeding data limitations", models.FieldError{
+			Field:   "ads",
+			Code:    code,
+			Message: fmt.Sprintf("ads should have from 1 to %d items", maxBatchSize),
+		})
+		return
+	}
+	resp := models.CreatedAds{Results: make([]models.BatchAdResult, len(batch.Ads))}
+	knownCategories := make(map[string]bool)
+	var validAds []models.CreatingAd
+	var validIndexes []int
+	for i, adData := range batch.Ads {
+		resp.Results[i].Index = i
+		if fieldErrors := server.AdLimits.ValidateAd(adData); len(fieldErrors) > 0 {
+			resp.Results[i].Code = problem.CodeValidationFailed
+			resp.Results[i].Errors = fieldErrors
+			continue
+		}
+		known, checked := knownCategories[adData.CategoryID]
+		if !checked {
+			category, err := server.DBManager.SelectCategory(r.Context(), adData.CategoryID)
+			if err != nil {
+				logging.FromContext(r.Context()).Errorf("couldn't get category with id %s from db. err: [%s]", adData.CategoryID, err)
+				writeDBError(w, err, "error getting category from db")
+				return
+			}
+			known = category != nil
+			knownCategories[adData.CategoryID] = known
+		}
+		if !known {
+			resp.Results[i].Code = problem.CodeUnknownCategory
+			resp.Results[i].Errors = []models.FieldError{{Field: "categoryID", Code: validation.CodeInvalid, Message: "category doesn't exist"}}
+			continue
+		}
+		validAds = append(validAds, adData)
+		validIndexes = append(validIndexes, i)
+	}
+	if len(validAds) > 0 {
+		adIDs, err := server.DBManager.NewAds(r.Context(), validAds, ownerID)
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("couldn't create %d ads in db. err: [%s]", len(validAds), err)
+			writeDBError(w, err, "error creating ads in db")
+			return
+		}
+		for i, adID := range adIDs {
+			resp.Results[validIndexes[i]].AdID = adID
+		}
+	}
+	resp.Created = len(validAds)
+	resp.Failed = len(batch.Ads) - len(validAds)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(resp)
+}
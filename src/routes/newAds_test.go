@@ -0,0 +1,120 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIServer_NewAds(t *testing.T) {
+	const validAd = `{"title":"title","description":"description","photoLinks":["https://ya.ru"],"price":100,"categoryID":"%s"}`
+	tests := []struct {
+		server             APIServer
+		name               string
+		ads                []string
+		expectedOutputCode int
+		expectedCreated    int
+		expectedCodes      []string
+	}{
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Create all ads",
+			ads:                []string{validAd, validAd, validAd},
+			expectedOutputCode: http.StatusOK,
+			expectedCreated:    3,
+			expectedCodes:      []string{"", "", ""},
+		},
+		{
+			server: APIServer{DBManager: db.NewMockedDBManager()},
+			name:   "Validate every ad independently",
+			ads: []string{
+				validAd,
+				`{"title":"","description":"description","photoLinks":["https://ya.ru"],"price":100,"categoryID":"%s"}`,
+				`{"title":"title","description":"description","photoLinks":["https://ya.ru"],"price":100,"categoryID":"22e88a53-3c80-429d-9e84-99d217788098"}`,
+				validAd,
+			},
+			expectedOutputCode: http.StatusOK,
+			expectedCreated:    2,
+			expectedCodes:      []string{"", "validation_failed", "unknown_category", ""},
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Nothing valid",
+			ads:                []string{`{"title":"title","description":"description","photoLinks":[],"price":100,"categoryID":"%s"}`},
+			expectedOutputCode: http.StatusOK,
+			expectedCreated:    0,
+			expectedCodes:      []string{"validation_failed"},
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Empty batch",
+			ads:                []string{},
+			expectedOutputCode: http.StatusBadRequest,
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager(), MaxBatchSize: 2},
+			name:               "Too big batch",
+			ads:                []string{validAd, validAd, validAd},
+			expectedOutputCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.server.DBManager.Close()
+			categoryID := populateCategory(t, tt.server)
+			ownerID := populateUser(t, tt.server)
+			ads := make([]string, len(tt.ads))
+			for i, ad := range tt.ads {
+				if strings.Contains(ad, "%s") {
+					ad = fmt.Sprintf(ad, categoryID)
+				}
+				ads[i] = ad
+			}
+			request, err := newRequest(http.MethodPost, "/ads:batch", bytes.NewBufferString(`{"ads":[`+strings.Join(ads, ",")+`]}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			tt.server.NewAds(rr, asUser(request, ownerID))
+			assert.Equal(t, tt.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode))
+			count, err := tt.server.DBManager.CountAds(context.Background(), models.AdsFilter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, int64(tt.expectedCreated), count)
+			if tt.expectedOutputCode != http.StatusOK {
+				return
+			}
+			var resp models.CreatedAds
+			err = json.Unmarshal(rr.Body.Bytes(), &resp)
+			if err != nil {
+				t.Fatalf("unexpected output: %v", err)
+			}
+			assert.Equal(t, tt.expectedCreated, resp.Created)
+			assert.Equal(t, len(tt.ads)-tt.expectedCreated, resp.Failed)
+			codes := make([]string, len(resp.Results))
+			for i, result := range resp.Results {
+				assert.Equal(t, i, result.Index)
+				codes[i] = result.Code
+				if result.Code == "" {
+					ad, err := tt.server.DBManager.SelectAd(context.Background(), result.AdID)
+					assert.NoError(t, err)
+					assert.Equal(t, ownerID, ad.OwnerID)
+				} else {
+					assert.Empty(t, result.AdID)
+					assert.NotEmpty(t, result.Errors)
+				}
+			}
+			assert.Equal(t, tt.expectedCodes, codes)
+		})
+	}
+}
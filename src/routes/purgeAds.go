@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"adv-backend-trainee-assignment/src/logging"
+	"adv-backend-trainee-assignment/src/models"
+	"adv-backend-trainee-assignment/src/problem"
+)
+
+func (server APIServer) PurgeDeletedAds(w http.ResponseWriter, r *http.Request) {
+	deletedBefore, err := parseTimeParam(r.URL.Query(), "deletedbefore")
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, problem.CodeInvalidParameter, err.Error())
+		return
+	} else if deletedBefore == nil {
+		problem.Write(w, http.StatusBadRequest, problem.CodeInvalidParameter, "deletedBefore is required")
+		return
+	}
+	purged, err := server.DBManager.PurgeDeletedAds(r.Context(), *deletedBefore)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("couldn't purge soft deleted ads. err: [%s]", err)
+		writeDBError(w, err, "error purging ads in db")
+	} else {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(models.PurgedAds{Purged: purged})
+	}
+}
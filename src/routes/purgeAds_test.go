@@ -0,0 +1,108 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"adv-backend-trainee-assignment/src/db"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIServer_PurgeDeletedAds(t *testing.T) {
+	tests := []struct {
+		name               string
+		url                string
+		expectedOutputCode int
+		expectedPurged     int64
+		expectedListLength int
+	}{
+		{
+			name:               "Purge ads deleted before now",
+			url:                fmt.Sprintf("/admin/ads/purge?deletedbefore=%d", time.Now().Add(time.Minute).Unix()),
+			expectedOutputCode: http.StatusOK,
+			expectedPurged:     1,
+			expectedListLength: 1,
+		},
+		{
+			name:               "Nothing to purge",
+			url:                "/admin/ads/purge?deletedbefore=2009-11-10t23:00:00z",
+			expectedOutputCode: http.StatusOK,
+			expectedPurged:     0,
+			expectedListLength: 1,
+		},
+		{
+			name:               "Missing deletedBefore",
+			url:                "/admin/ads/purge",
+			expectedOutputCode: http.StatusBadRequest,
+			expectedListLength: 1,
+		},
+		{
+			name:               "Bad deletedBefore",
+			url:                "/admin/ads/purge?deletedbefore=yesterday",
+			expectedOutputCode: http.StatusBadRequest,
+			expectedListLength: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := APIServer{DBManager: db.NewMockedDBManager()}
+			defer server.DBManager.Close()
+			router := mux.NewRouter()
+			for _, route := range GenerateRoutes(server) {
+				router.Methods(route.Method).Path(route.Pattern).Handler(route.HandlerFunc)
+			}
+			ownerID := populateUser(t, server)
+			categoryID := populateCategory(t, server)
+			adIDs := make([]string, 2)
+			for i := range adIDs {
+				request, err := newRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(withCategory(t, `{"title":"title","description":"description","photoLinks":["http://google.com"],"price":100}`, categoryID))))
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, asUser(request, ownerID))
+				var tmpData map[string]string
+				if err = json.Unmarshal(rr.Body.Bytes(), &tmpData); err != nil {
+					t.Fatalf("unexpected output: %v", err)
+				}
+				adIDs[i] = tmpData["ad_id"]
+			}
+			request, err := newRequest(http.MethodDelete, "/ads/"+adIDs[0], nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), asUser(request, ownerID))
+			request, err = newRequest(http.MethodPost, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+			assert.Equal(t, tt.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode))
+			if tt.expectedOutputCode == http.StatusOK {
+				var tmpData map[string]int64
+				if err = json.Unmarshal(rr.Body.Bytes(), &tmpData); err != nil {
+					t.Fatalf("unexpected output: %v", err)
+				}
+				assert.Equal(t, tt.expectedPurged, tmpData["purged"])
+			}
+			request, err = newRequest(http.MethodGet, "/ads", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr = httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+			var ads []interface{}
+			if err = json.Unmarshal(rr.Body.Bytes(), &ads); err != nil {
+				t.Fatalf("unexpected output: %v", err)
+			}
+			assert.Equal(t, tt.expectedListLength, len(ads))
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"net/http"
+
+	"adv-backend-trainee-assignment/src/logging"
+	"adv-backend-trainee-assignment/src/problem"
+	"github.com/gorilla/mux"
+)
+
+func (server APIServer) RestoreAd(w http.ResponseWriter, r *http.Request) {
+	if adID, ok := mux.Vars(r)["adID"]; ok {
+		restored, err := server.DBManager.RestoreAd(r.Context(), adID)
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("couldn't restore ad with id %s in db. err: [%s]", adID, err)
+			writeDBError(w, err, "error restoring ad in db")
+		} else if !restored {
+			problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "deleted ad not found")
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	} else {
+		problem.Write(w, http.StatusBadRequest, problem.CodeInvalidParameter, "couldn't extract ad id from urlFormat")
+	}
+}
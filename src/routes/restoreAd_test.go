@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIServer_RestoreAd(t *testing.T) {
+	tests := []struct {
+		server             APIServer
+		name               string
+		population         string
+		deleteBefore       bool
+		expectedOutputCode int
+		expectedListLength int
+	}{
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Restore deleted ad",
+			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru"],"price":100}`,
+			deleteBefore:       true,
+			expectedOutputCode: http.StatusNoContent,
+			expectedListLength: 1,
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Restore not deleted ad",
+			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru"],"price":100}`,
+			deleteBefore:       false,
+			expectedOutputCode: http.StatusNotFound,
+			expectedListLength: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.server.DBManager.Close()
+			router := mux.NewRouter()
+			router.HandleFunc("/admin/ads/{adID}/restore", tt.server.RestoreAd)
+			ownerID := populateUser(t, tt.server)
+			request, err := newRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(withCategory(t, tt.population, populateCategory(t, tt.server)))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			tt.server.NewAd(rr, asUser(request, ownerID))
+			var tmpData map[string]string
+			err = json.Unmarshal(rr.Body.Bytes(), &tmpData)
+			if err != nil {
+				t.Fatalf("unexpected output: %v", err)
+			}
+			if tt.deleteBefore {
+				_, err = tt.server.DBManager.DeleteAd(context.Background(), tmpData["ad_id"])
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			request, err = newRequest(http.MethodPost, fmt.Sprintf("/admin/ads/%v/restore", tmpData["ad_id"]), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr = httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+			assert.Equal(t, tt.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode))
+			ads, err := tt.server.DBManager.GetAllAds(context.Background(), "created_at", "desc", 1, 10, models.AdsFilter{}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.expectedListLength, len(ads), fmt.Sprintf("wrong output length: got %v expected %v", len(ads), tt.expectedListLength))
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"adv-backend-trainee-assignment/src/logging"
+	"adv-backend-trainee-assignment/src/models"
+	"adv-backend-trainee-assignment/src/problem"
+)
+
+func convertFoundAdToSearchedAd(foundAd *models.FoundAd) *models.SearchedAd {
+	return &models.SearchedAd{
+		BasicAd: *convertDBAdToBasicAd(&foundAd.DbAd),
+		Rank:    foundAd.Rank,
+		Snippet: foundAd.Snippet,
+	}
+}
+
+func (server APIServer) SearchAds(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := strings.TrimSpace(q.Get("q"))
+	if query == "" {
+		problem.Write(w, http.StatusBadRequest, problem.CodeInvalidParameter, "empty search query")
+		return
+	}
+	page, perPage := parsePagination(q)
+	foundAds, err := server.DBManager.SearchAds(r.Context(), query, page, perPage, q.Get("highlight") == "true")
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("couldn't search ads in db. err: [%s]", err)
+		writeDBError(w, err, "error searching ads in db")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		resp := make([]*models.SearchedAd, 0, len(foundAds))
+		for _, foundAd := range foundAds {
+			resp = append(resp, convertFoundAdToSearchedAd(foundAd))
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,126 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"adv-backend-trainee-assignment/src/db"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIServer_SearchAds(t *testing.T) {
+	population := []string{
+		`{"title":"Red bicycle","description":"Almost new bicycle, red frame","photoLinks":["https://ya.ru"],"price":100}`,
+		`{"title":"Garage sale","description":"Old chairs, a table and a red bicycle","photoLinks":["https://ya.ru"],"price":50}`,
+		`{"title":"Велосипед","description":"Горный велосипед, почти новый","photoLinks":["https://ya.ru"],"price":150}`,
+	}
+	tests := []struct {
+		server              APIServer
+		name                string
+		url                 string
+		expectedOutputCode  int
+		expectedOutputOrder []int
+		expectedSnippets    []string
+	}{
+		{
+			server:              APIServer{DBManager: db.NewMockedDBManager()},
+			name:                "Title matches rank higher",
+			url:                 "/ads/search?q=bicycle",
+			expectedOutputCode:  http.StatusOK,
+			expectedOutputOrder: []int{1, 2},
+		},
+		{
+			server:              APIServer{DBManager: db.NewMockedDBManager()},
+			name:                "All words must match",
+			url:                 "/ads/search?q=red%20chairs",
+			expectedOutputCode:  http.StatusOK,
+			expectedOutputOrder: []int{2},
+		},
+		{
+			server:              APIServer{DBManager: db.NewMockedDBManager()},
+			name:                "Cyrillic words",
+			url:                 "/ads/search?q=ВЕЛОСИПЕД",
+			expectedOutputCode:  http.StatusOK,
+			expectedOutputOrder: []int{3},
+		},
+		{
+			server:              APIServer{DBManager: db.NewMockedDBManager()},
+			name:                "Highlighted snippets",
+			url:                 "/ads/search?q=chairs&highlight=true",
+			expectedOutputCode:  http.StatusOK,
+			expectedOutputOrder: []int{2},
+			expectedSnippets:    []string{"Garage sale Old <b>chairs,</b> a table and a red bicycle"},
+		},
+		{
+			server:              APIServer{DBManager: db.NewMockedDBManager()},
+			name:                "Nothing found",
+			url:                 "/ads/search?q=car",
+			expectedOutputCode:  http.StatusOK,
+			expectedOutputOrder: []int{},
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Empty query",
+			url:                "/ads/search?q=",
+			expectedOutputCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.server.DBManager.Close()
+			router := mux.NewRouter()
+			for _, route := range GenerateRoutes(tt.server) {
+				router.Methods(route.Method).Path(route.Pattern).Handler(route.HandlerFunc)
+			}
+			categoryID := populateCategory(t, tt.server)
+			ownerID := populateUser(t, tt.server)
+			adIDs := make([]string, len(population))
+			for i, insertData := range population {
+				request, err := newRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(withCategory(t, insertData, categoryID))))
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, asUser(request, ownerID))
+				var tmpData map[string]string
+				err = json.Unmarshal(rr.Body.Bytes(), &tmpData)
+				if err != nil {
+					t.Fatalf("unexpected output: %v", err)
+				}
+				adIDs[i] = tmpData["ad_id"]
+			}
+			request, err := newRequest(http.MethodGet, strings.ToLower(tt.url), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+			assert.Equal(t, tt.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode))
+			if tt.expectedOutputCode != http.StatusOK {
+				return
+			}
+			var tmpData []map[string]interface{}
+			err = json.Unmarshal(rr.Body.Bytes(), &tmpData)
+			if err != nil {
+				t.Fatalf("unexpected output: %v", err)
+			}
+			assert.Equal(t, len(tt.expectedOutputOrder), len(tmpData), fmt.Sprintf("wrong output length: got %v expected %v", len(tmpData), len(tt.expectedOutputOrder)))
+			for x, a := range tt.expectedOutputOrder {
+				if x >= len(tmpData) || adIDs[a-1] != tmpData[x]["adID"] {
+					t.Errorf("wrong elements order: got %v expected %v", tmpData, tt.expectedOutputOrder)
+					break
+				}
+				assert.Greater(t, tmpData[x]["rank"], 0.0, "rank isn't positive")
+			}
+			for x, snippet := range tt.expectedSnippets {
+				assert.Equal(t, snippet, tmpData[x]["snippet"])
+			}
+		})
+	}
+}
@@ -5,9 +5,10 @@ import (
 	"net/http"
 	"strings"
 
+	"adv-backend-trainee-assignment/src/logging"
 	"adv-backend-trainee-assignment/src/models"
+	"adv-backend-trainee-assignment/src/problem"
 	"github.com/gorilla/mux"
-	log "github.com/sirupsen/logrus"
 )
 
 func convertDBAdToExtendedAd(dbAd *models.DbAd) *models.ExtendedAd {
@@ -15,6 +16,8 @@ func convertDBAdToExtendedAd(dbAd *models.DbAd) *models.ExtendedAd {
 		Title:         dbAd.Title,
 		Price:         dbAd.Price,
 		MainPhotoLink: dbAd.PhotoLinks[0],
+		CategoryID:    dbAd.CategoryID,
+		OwnerID:       dbAd.OwnerID,
 		Description:   dbAd.Description,
 		PhotoLinks:    dbAd.PhotoLinks,
 	}
@@ -22,36 +25,34 @@ func convertDBAdToExtendedAd(dbAd *models.DbAd) *models.ExtendedAd {
 
 func (server APIServer) SelectAd(w http.ResponseWriter, r *http.Request) {
 	if adID, ok := mux.Vars(r)["adID"]; ok {
-		adData, err := server.DBManager.SelectAd(adID)
+		adData, err := server.DBManager.SelectAd(r.Context(), adID)
 		if err != nil {
-			log.Errorf("couldn't get ad with id %s from db. err: [%s]", adID, err)
-			http.Error(w, "error getting ad from db", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Errorf("couldn't get ad with id %s from db. err: [%s]", adID, err)
+			writeDBError(w, err, "error getting ad from db")
+		} else if adData == nil {
+			problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "ad not found")
 		} else {
 			w.Header().Set("Content-Type", "application/json")
-			if adData != nil {
-				fullAd := convertDBAdToExtendedAd(adData)
-				fields := r.URL.Query().Get("fields")
-				fullAd.Description = ""
-				fullAd.PhotoLinks = []string{}
-				if fields != "" {
-					for _, word := range strings.Split(fields, ",") {
-						switch word {
-						case "description":
-							fullAd.Description = adData.Description
-						case "photolinks":
-							fullAd.PhotoLinks = adData.PhotoLinks
-						}
-						if fullAd.Description != "" && len(fullAd.PhotoLinks) != 0 {
-							break
-						}
+			fullAd := convertDBAdToExtendedAd(adData)
+			fields := r.URL.Query().Get("fields")
+			fullAd.Description = ""
+			fullAd.PhotoLinks = []string{}
+			if fields != "" {
+				for _, word := range strings.Split(fields, ",") {
+					switch word {
+					case "description":
+						fullAd.Description = adData.Description
+					case "photolinks":
+						fullAd.PhotoLinks = adData.PhotoLinks
+					}
+					if fullAd.Description != "" && len(fullAd.PhotoLinks) != 0 {
+						break
 					}
 				}
-				_ = json.NewEncoder(w).Encode(fullAd)
-			} else {
-				_, _ = w.Write([]byte("{}"))
 			}
+			_ = json.NewEncoder(w).Encode(fullAd)
 		}
 	} else {
-		http.Error(w, "couldn't extract ad id from urlFormat", http.StatusBadRequest)
+		problem.Write(w, http.StatusBadRequest, problem.CodeInvalidParameter, "couldn't extract ad id from urlFormat")
 	}
 }
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/models"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 )
@@ -31,7 +32,7 @@ func TestAPIServer_SelectAd(t *testing.T) {
 		expectedOutput     ExtendedAd
 	}{
 		{
-			server:             APIServer{db.NewMockedDBManager()},
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
 			name:               "Get single ad",
 			urlFormat:          "/ads/%v",
 			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":100}`,
@@ -39,7 +40,7 @@ func TestAPIServer_SelectAd(t *testing.T) {
 			expectedOutput:     ExtendedAd{Title: "title 1", Price: 100, MainPhotoLink: "https://ya.ru"},
 		},
 		{
-			server:             APIServer{db.NewMockedDBManager()},
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
 			name:               "Get single ad with description",
 			urlFormat:          "/ads/%v?fields=description",
 			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":100}`,
@@ -47,7 +48,7 @@ func TestAPIServer_SelectAd(t *testing.T) {
 			expectedOutput:     ExtendedAd{Title: "title 1", Price: 100, MainPhotoLink: "https://ya.ru", Description: "description 1"},
 		},
 		{
-			server:             APIServer{db.NewMockedDBManager()},
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
 			name:               "Get single ad with description and photo links",
 			urlFormat:          "/ads/%v?fields=description,photolinks",
 			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":100}`,
@@ -55,7 +56,7 @@ func TestAPIServer_SelectAd(t *testing.T) {
 			expectedOutput:     ExtendedAd{Title: "title 1", Price: 100, MainPhotoLink: "https://ya.ru", Description: "description 1", PhotoLinks: []string{"https://ya.ru", "http://google.com", "https://example.com"}},
 		},
 		{
-			server:             APIServer{db.NewMockedDBManager()},
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
 			name:               "Get single ad with description and photolinks with other parameters order",
 			urlFormat:          "/ads/%v?fields=photolinks,description",
 			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":100}`,
@@ -63,7 +64,7 @@ func TestAPIServer_SelectAd(t *testing.T) {
 			expectedOutput:     ExtendedAd{Title: "title 1", Price: 100, MainPhotoLink: "https://ya.ru", Description: "description 1", PhotoLinks: []string{"https://ya.ru", "http://google.com", "https://example.com"}},
 		},
 		{
-			server:             APIServer{db.NewMockedDBManager()},
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
 			name:               "Get single ad with photo links",
 			urlFormat:          "/ads/%v?fields=photolinks",
 			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":100}`,
@@ -71,7 +72,7 @@ func TestAPIServer_SelectAd(t *testing.T) {
 			expectedOutput:     ExtendedAd{Title: "title 1", Price: 100, MainPhotoLink: "https://ya.ru", PhotoLinks: []string{"https://ya.ru", "http://google.com", "https://example.com"}},
 		},
 		{
-			server:             APIServer{db.NewMockedDBManager()},
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
 			name:               "Get single ad with photo links with following comma",
 			urlFormat:          "/ads/%v?fields=photolinks,",
 			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":100}`,
@@ -79,7 +80,7 @@ func TestAPIServer_SelectAd(t *testing.T) {
 			expectedOutput:     ExtendedAd{Title: "title 1", Price: 100, MainPhotoLink: "https://ya.ru", PhotoLinks: []string{"https://ya.ru", "http://google.com", "https://example.com"}},
 		},
 		{
-			server:             APIServer{db.NewMockedDBManager()},
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
 			name:               "Get single ad with no additional parameters but comma",
 			urlFormat:          "/ads/%v?fields=,",
 			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com","https://example.com"],"price":100}`,
@@ -91,19 +92,20 @@ func TestAPIServer_SelectAd(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			router := mux.NewRouter()
 			router.HandleFunc("/ads/{adID}", tt.server.SelectAd)
-			request, err := http.NewRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(tt.population)))
+			ownerID := populateUser(t, tt.server)
+			request, err := newRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(withCategory(t, tt.population, populateCategory(t, tt.server)))))
 			if err != nil {
 				t.Fatal(err)
 			}
 			rr := httptest.NewRecorder()
-			tt.server.NewAd(rr, request)
+			tt.server.NewAd(rr, asUser(request, ownerID))
 			assert.Equal(t, tt.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode))
 			var tmpData map[string]interface{}
 			err = json.Unmarshal(rr.Body.Bytes(), &tmpData)
 			if err != nil {
 				t.Errorf("unexpected output: %v", err)
 			}
-			request, err = http.NewRequest(http.MethodGet, fmt.Sprintf(tt.urlFormat, tmpData["ad_id"]), bytes.NewBuffer([]byte(tt.population)))
+			request, err = newRequest(http.MethodGet, fmt.Sprintf(tt.urlFormat, tmpData["ad_id"]), bytes.NewBuffer([]byte(withCategory(t, tt.population, populateCategory(t, tt.server)))))
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -120,3 +122,24 @@ func TestAPIServer_SelectAd(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIServer_SelectAdNotFound(t *testing.T) {
+	server := APIServer{DBManager: db.NewMockedDBManager()}
+	defer server.DBManager.Close()
+	router := mux.NewRouter()
+	router.HandleFunc("/ads/{adID}", server.SelectAd)
+	request, err := newRequest(http.MethodGet, "/ads/22e88a53-3c80-429d-9e84-99d217788098", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, request)
+	assert.Equal(t, http.StatusNotFound, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, http.StatusNotFound))
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	var problem models.Problem
+	err = json.Unmarshal(rr.Body.Bytes(), &problem)
+	if err != nil {
+		t.Fatalf("unexpected output: %v", err)
+	}
+	assert.Equal(t, models.Problem{Type: "about:blank", Title: "Not Found", Status: http.StatusNotFound, Code: "not_found", Detail: "ad not found"}, problem)
+}
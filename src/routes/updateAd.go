@@ -0,0 +1,51 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"adv-backend-trainee-assignment/src/logging"
+	"adv-backend-trainee-assignment/src/models"
+	"adv-backend-trainee-assignment/src/problem"
+	"github.com/gorilla/mux"
+)
+
+func emptyUpdatingAd(adData models.UpdatingAd) bool {
+	return adData.Title == nil && adData.Description == nil && adData.PhotoLinks == nil && adData.Price == nil && adData.CategoryID == nil
+}
+
+func (server APIServer) UpdateAd(w http.ResponseWriter, r *http.Request) {
+	adID, ok := mux.Vars(r)["adID"]
+	if !ok {
+		problem.Write(w, http.StatusBadRequest, problem.CodeInvalidParameter, "couldn't extract ad id from urlFormat")
+		return
+	}
+	var adData models.UpdatingAd
+	if !server.parseRequest(w, r, &adData) {
+		return
+	}
+	if emptyUpdatingAd(adData) {
+		problem.Write(w, http.StatusBadRequest, problem.CodeValidationFailed, "no fields to update")
+		return
+	}
+	if fieldErrors := server.AdLimits.ValidateUpdatingAd(adData, r.Method == http.MethodPut); len(fieldErrors) > 0 {
+		problem.Write(w, http.StatusBadRequest, problem.CodeValidationFailed, "exceeding data limitations", fieldErrors...)
+		return
+	}
+	if !server.authorizeAdOwner(w, r, adID) {
+		return
+	}
+	if adData.CategoryID != nil && !server.checkCategory(w, r, *adData.CategoryID) {
+		return
+	}
+	updatedAd, err := server.DBManager.UpdateAd(r.Context(), adID, adData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("couldn't update ad with id %s in db. err: [%s]", adID, err)
+		writeDBError(w, err, "error updating ad in db")
+	} else if updatedAd == nil {
+		problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "ad not found")
+	} else {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(convertDBAdToExtendedAd(updatedAd))
+	}
+}
@@ -0,0 +1,147 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"adv-backend-trainee-assignment/src/db"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIServer_UpdateAd(t *testing.T) {
+	type ExtendedAd struct {
+		Title         string   `json:"title"`
+		Price         int64    `json:"price"`
+		MainPhotoLink string   `json:"mainPhotoLink"`
+		Description   string   `json:"description,omitempty"`
+		PhotoLinks    []string `json:"photoLinks,omitempty"`
+	}
+
+	tests := []struct {
+		server             APIServer
+		name               string
+		method             string
+		population         string
+		body               string
+		unknownAd          bool
+		expectedOutputCode int
+		expectedOutput     ExtendedAd
+	}{
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Patch title",
+			method:             http.MethodPatch,
+			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com"],"price":100}`,
+			body:               `{"title":"new title"}`,
+			expectedOutputCode: http.StatusOK,
+			expectedOutput:     ExtendedAd{Title: "new title", Price: 100, MainPhotoLink: "https://ya.ru", Description: "description 1", PhotoLinks: []string{"https://ya.ru", "http://google.com"}},
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Patch price and photo links",
+			method:             http.MethodPatch,
+			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com"],"price":100}`,
+			body:               `{"price":250,"photoLinks":["https://example.com"]}`,
+			expectedOutputCode: http.StatusOK,
+			expectedOutput:     ExtendedAd{Title: "title 1", Price: 250, MainPhotoLink: "https://example.com", Description: "description 1", PhotoLinks: []string{"https://example.com"}},
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Patch with too small price",
+			method:             http.MethodPatch,
+			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com"],"price":100}`,
+			body:               `{"price":0}`,
+			expectedOutputCode: http.StatusBadRequest,
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Patch with too many photo links",
+			method:             http.MethodPatch,
+			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com"],"price":100}`,
+			body:               `{"photoLinks":["https://ya.ru","http://google.com","https://example.com","https://yandex.ru"]}`,
+			expectedOutputCode: http.StatusBadRequest,
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Patch with empty body",
+			method:             http.MethodPatch,
+			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com"],"price":100}`,
+			body:               `{}`,
+			expectedOutputCode: http.StatusBadRequest,
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Put full ad",
+			method:             http.MethodPut,
+			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com"],"price":100}`,
+			body:               `{"title":"title 2","description":"description 2","photoLinks":["https://example.com"],"price":15}`,
+			expectedOutputCode: http.StatusOK,
+			expectedOutput:     ExtendedAd{Title: "title 2", Price: 15, MainPhotoLink: "https://example.com", Description: "description 2", PhotoLinks: []string{"https://example.com"}},
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Put partial ad",
+			method:             http.MethodPut,
+			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com"],"price":100}`,
+			body:               `{"title":"title 2"}`,
+			expectedOutputCode: http.StatusBadRequest,
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Patch not existing ad",
+			method:             http.MethodPatch,
+			population:         `{"title":"title 1","description":"description 1","photoLinks":["https://ya.ru","http://google.com"],"price":100}`,
+			body:               `{"title":"new title"}`,
+			unknownAd:          true,
+			expectedOutputCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.server.DBManager.Close()
+			router := mux.NewRouter()
+			router.HandleFunc("/ads/{adID}", tt.server.UpdateAd)
+			categoryID := populateCategory(t, tt.server)
+			ownerID := populateUser(t, tt.server)
+			request, err := newRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(withCategory(t, tt.population, categoryID))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			tt.server.NewAd(rr, asUser(request, ownerID))
+			var tmpData map[string]interface{}
+			err = json.Unmarshal(rr.Body.Bytes(), &tmpData)
+			if err != nil {
+				t.Fatalf("unexpected output: %v", err)
+			}
+			adID := tmpData["ad_id"]
+			if tt.unknownAd {
+				adID = "22e88a53-3c80-429d-9e84-99d217788098"
+			}
+			body := tt.body
+			if tt.method == http.MethodPut {
+				body = withCategory(t, body, categoryID)
+			}
+			request, err = newRequest(tt.method, fmt.Sprintf("/ads/%v", adID), bytes.NewBuffer([]byte(body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr = httptest.NewRecorder()
+			router.ServeHTTP(rr, asUser(request, ownerID))
+			assert.Equal(t, tt.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode))
+			if tt.expectedOutputCode == http.StatusOK {
+				var tmp ExtendedAd
+				err = json.Unmarshal(rr.Body.Bytes(), &tmp)
+				if err != nil {
+					t.Errorf("unexpected output: %v", err)
+				}
+				assert.Equal(t, tt.expectedOutput, tmp, fmt.Sprintf("unexpected output: got %v expected %v", tmp, tt.expectedOutput))
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/mail"
+
+	"adv-backend-trainee-assignment/src/auth"
+	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/logging"
+	"adv-backend-trainee-assignment/src/models"
+	"adv-backend-trainee-assignment/src/problem"
+	"github.com/gorilla/mux"
+)
+
+func validUserData(user models.CreatingUser) bool {
+	if !(1 <= len(user.Name) && len(user.Name) <= 100) {
+		return false
+	}
+	address, err := mail.ParseAddress(user.Email)
+	return err == nil && address.Address == user.Email
+}
+
+func (server APIServer) NewUser(w http.ResponseWriter, r *http.Request) {
+	var user models.CreatingUser
+	if !server.parseRequest(w, r, &user) {
+		return
+	}
+	if !validUserData(user) {
+		problem.Write(w, http.StatusBadRequest, problem.CodeValidationFailed, "exceeding data limitations")
+		return
+	}
+	userID, err := server.DBManager.NewUser(r.Context(), user)
+	if err == db.ErrEmailTaken {
+		problem.Write(w, http.StatusConflict, problem.CodeEmailTaken, "email is already taken")
+	} else if err != nil {
+		logging.FromContext(r.Context()).Errorf("couldn't create user in db. err: [%s]", err)
+		writeDBError(w, err, "error creating user in db")
+	} else {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(models.CreatedUser{UserID: userID})
+	}
+}
+
+func (server APIServer) SelectUser(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userID"]
+	user, err := server.DBManager.SelectUser(r.Context(), userID)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("couldn't get user with id %s from db. err: [%s]", userID, err)
+		writeDBError(w, err, "error getting user from db")
+	} else if user == nil {
+		problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "user not found")
+	} else {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if identity, ok := auth.FromContext(r.Context()); ok && (identity.UserID == user.UserID || identity.HasRole(moderators...)) {
+			_ = json.NewEncoder(w).Encode(user)
+		} else {
+			_ = json.NewEncoder(w).Encode(models.PublicUser{UserID: user.UserID, Name: user.Name, CreatedAt: user.CreatedAt})
+		}
+	}
+}
+
+func (server APIServer) GetUserAds(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userID"]
+	user, err := server.DBManager.SelectUser(r.Context(), userID)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("couldn't get user with id %s from db. err: [%s]", userID, err)
+		writeDBError(w, err, "error getting user from db")
+	} else if user == nil {
+		problem.Write(w, http.StatusNotFound, problem.CodeNotFound, "user not found")
+	} else {
+		server.GetAllAds(w, r)
+	}
+}
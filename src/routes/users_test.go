@@ -0,0 +1,335 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"adv-backend-trainee-assignment/src/auth"
+	"adv-backend-trainee-assignment/src/db"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIServer_NewUser(t *testing.T) {
+	tests := []struct {
+		server             APIServer
+		name               string
+		population         string
+		body               string
+		expectedOutputCode int
+	}{
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Create user",
+			body:               `{"name":"seller","email":"seller@example.com"}`,
+			expectedOutputCode: http.StatusOK,
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Empty name",
+			body:               `{"name":"","email":"seller@example.com"}`,
+			expectedOutputCode: http.StatusBadRequest,
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Invalid email",
+			body:               `{"name":"seller","email":"seller"}`,
+			expectedOutputCode: http.StatusBadRequest,
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Taken email",
+			population:         `{"name":"seller","email":"seller@example.com"}`,
+			body:               `{"name":"another seller","email":"SELLER@example.com"}`,
+			expectedOutputCode: http.StatusConflict,
+		},
+		{
+			server:             APIServer{DBManager: db.NewMockedDBManager()},
+			name:               "Invalid json",
+			body:               `{"name":"seller"`,
+			expectedOutputCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.server.DBManager.Close()
+			if tt.population != "" {
+				request, err := newRequest(http.MethodPost, "/users", bytes.NewBufferString(tt.population))
+				if err != nil {
+					t.Fatal(err)
+				}
+				tt.server.NewUser(httptest.NewRecorder(), request)
+			}
+			request, err := newRequest(http.MethodPost, "/users", bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			tt.server.NewUser(rr, request)
+			assert.Equal(t, tt.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode))
+			if tt.expectedOutputCode == http.StatusOK {
+				var tmpData map[string]string
+				err = json.Unmarshal(rr.Body.Bytes(), &tmpData)
+				if err != nil {
+					t.Fatalf("unexpected output: %v", err)
+				}
+				assert.NotEmpty(t, tmpData["userID"])
+			}
+		})
+	}
+}
+
+func TestAPIServer_SelectUser(t *testing.T) {
+	server := APIServer{DBManager: db.NewMockedDBManager()}
+	defer server.DBManager.Close()
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{userID}", server.SelectUser)
+	userID := populateUser(t, server)
+	tests := []struct {
+		name               string
+		userID             string
+		caller             string
+		roles              []string
+		expectedOutputCode int
+		expectedEmail      bool
+	}{
+		{
+			name:               "Anonymous caller",
+			userID:             userID,
+			expectedOutputCode: http.StatusOK,
+		},
+		{
+			name:               "Another seller",
+			userID:             userID,
+			caller:             "stranger",
+			expectedOutputCode: http.StatusOK,
+		},
+		{
+			name:               "User themself",
+			userID:             userID,
+			caller:             userID,
+			expectedOutputCode: http.StatusOK,
+			expectedEmail:      true,
+		},
+		{
+			name:               "Moderator",
+			userID:             userID,
+			caller:             "moderator",
+			roles:              []string{auth.RoleModerator},
+			expectedOutputCode: http.StatusOK,
+			expectedEmail:      true,
+		},
+		{
+			name:               "Unknown user",
+			userID:             "22e88a53-3c80-429d-9e84-99d217788098",
+			expectedOutputCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := newRequest(http.MethodGet, "/users/"+tt.userID, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.caller != "" {
+				request = asUser(request, tt.caller, tt.roles...)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+			assert.Equal(t, tt.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode))
+			if tt.expectedOutputCode == http.StatusOK {
+				var tmpData map[string]interface{}
+				err = json.Unmarshal(rr.Body.Bytes(), &tmpData)
+				if err != nil {
+					t.Fatalf("unexpected output: %v", err)
+				}
+				assert.Equal(t, tt.userID, tmpData["userID"])
+				assert.Equal(t, "seller", tmpData["name"])
+				assert.NotEmpty(t, tmpData["createdAt"])
+				_, hasEmail := tmpData["email"]
+				assert.Equal(t, tt.expectedEmail, hasEmail)
+			}
+		})
+	}
+}
+
+func TestAPIServer_GetUserAds(t *testing.T) {
+	server := APIServer{DBManager: db.NewMockedDBManager()}
+	defer server.DBManager.Close()
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{userID}/ads", server.GetUserAds)
+	categoryID := populateCategory(t, server)
+	seller := populateUser(t, server)
+	anotherSeller := populateUser(t, server)
+	idleSeller := populateUser(t, server)
+	population := map[string]int{seller: 2, anotherSeller: 1}
+	for userID, count := range population {
+		for i := 0; i < count; i++ {
+			request, err := newRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(withCategory(t, `{"title":"title","description":"description","photoLinks":["http://google.com"],"price":100}`, categoryID))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			server.NewAd(httptest.NewRecorder(), asUser(request, userID))
+		}
+	}
+	tests := []struct {
+		name               string
+		userID             string
+		expectedOutputCode int
+		expectedListLength int
+	}{
+		{
+			name:               "Seller with two ads",
+			userID:             seller,
+			expectedOutputCode: http.StatusOK,
+			expectedListLength: 2,
+		},
+		{
+			name:               "Seller with one ad",
+			userID:             anotherSeller,
+			expectedOutputCode: http.StatusOK,
+			expectedListLength: 1,
+		},
+		{
+			name:               "Seller without ads",
+			userID:             idleSeller,
+			expectedOutputCode: http.StatusOK,
+			expectedListLength: 0,
+		},
+		{
+			name:               "Unknown user",
+			userID:             "22e88a53-3c80-429d-9e84-99d217788098",
+			expectedOutputCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := newRequest(http.MethodGet, fmt.Sprintf("/users/%s/ads", tt.userID), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+			assert.Equal(t, tt.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode))
+			if tt.expectedOutputCode == http.StatusOK {
+				var tmpData []interface{}
+				err = json.Unmarshal(rr.Body.Bytes(), &tmpData)
+				if err != nil {
+					t.Fatalf("unexpected output: %v", err)
+				}
+				assert.Equal(t, tt.expectedListLength, len(tmpData))
+			}
+		})
+	}
+}
+
+func TestAPIServer_AdOwnership(t *testing.T) {
+	tests := []struct {
+		name               string
+		method             string
+		body               string
+		caller             string
+		roles              []string
+		expectedOutputCode int
+	}{
+		{
+			name:               "Owner updates ad",
+			method:             http.MethodPatch,
+			body:               `{"price":200}`,
+			caller:             "owner",
+			expectedOutputCode: http.StatusOK,
+		},
+		{
+			name:               "Another seller updates ad",
+			method:             http.MethodPatch,
+			body:               `{"price":200}`,
+			caller:             "stranger",
+			expectedOutputCode: http.StatusForbidden,
+		},
+		{
+			name:               "Anonymous updates ad",
+			method:             http.MethodPatch,
+			body:               `{"price":200}`,
+			expectedOutputCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Unknown user updates ad",
+			method:             http.MethodPatch,
+			body:               `{"price":200}`,
+			caller:             "22e88a53-3c80-429d-9e84-99d217788098",
+			expectedOutputCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Owner deletes ad",
+			method:             http.MethodDelete,
+			caller:             "owner",
+			expectedOutputCode: http.StatusNoContent,
+		},
+		{
+			name:               "Another seller deletes ad",
+			method:             http.MethodDelete,
+			caller:             "stranger",
+			expectedOutputCode: http.StatusForbidden,
+		},
+		{
+			name:               "Moderator deletes ad",
+			method:             http.MethodDelete,
+			caller:             "moderator",
+			roles:              []string{auth.RoleModerator},
+			expectedOutputCode: http.StatusNoContent,
+		},
+		{
+			name:               "Admin updates ad",
+			method:             http.MethodPatch,
+			body:               `{"price":200}`,
+			caller:             "admin",
+			roles:              []string{auth.RoleAdmin},
+			expectedOutputCode: http.StatusOK,
+		},
+		{
+			name:               "Anonymous deletes ad",
+			method:             http.MethodDelete,
+			expectedOutputCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := APIServer{DBManager: db.NewMockedDBManager()}
+			defer server.DBManager.Close()
+			router := mux.NewRouter()
+			for _, route := range GenerateRoutes(server) {
+				router.Methods(route.Method).Path(route.Pattern).Handler(route.HandlerFunc)
+			}
+			callers := map[string]string{"owner": populateUser(t, server), "stranger": populateUser(t, server)}
+			request, err := newRequest(http.MethodPost, "/ad", bytes.NewBuffer([]byte(withCategory(t, `{"title":"title","description":"description","photoLinks":["http://google.com"],"price":100}`, populateCategory(t, server)))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, asUser(request, callers["owner"]))
+			var tmpData map[string]string
+			err = json.Unmarshal(rr.Body.Bytes(), &tmpData)
+			if err != nil {
+				t.Fatalf("unexpected output: %v", err)
+			}
+			request, err = newRequest(tt.method, fmt.Sprintf("/ads/%s", tmpData["ad_id"]), bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			caller, ok := callers[tt.caller]
+			if !ok {
+				caller = tt.caller
+			}
+			if caller != "" {
+				request = asUser(request, caller, tt.roles...)
+			}
+			rr = httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+			assert.Equal(t, tt.expectedOutputCode, rr.Code, fmt.Sprintf("unexpected http code: got %v expected %v", rr.Code, tt.expectedOutputCode))
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package tracing
+
+import (
+	"context"
+
+	"adv-backend-trainee-assignment/src/db"
+)
+
+// DBHook starts a client span for every call to a db.Instrumented connection.
+func (tracer *Tracer) DBHook(dbSystem string) db.Hook {
+	return func(ctx context.Context, call db.Call) (context.Context, func(err error)) {
+		ctx, span := tracer.Start(ctx, "db."+call.Method, SpanKindClient)
+		span.SetAttribute("db.system", dbSystem)
+		span.SetAttribute("db.operation", call.Method)
+		if call.Method == "NewAds" {
+			span.SetAttribute("db.rows", call.Rows)
+		}
+		return ctx, func(err error) {
+			span.RecordError(err)
+			span.Finish()
+		}
+	}
+}
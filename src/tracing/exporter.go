@@ -0,0 +1,197 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Exporter interface {
+	Export(serviceName string, spans []*Span) error
+	Close() error
+}
+
+type (
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              SpanKind        `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpScopeSpans struct {
+		Scope struct {
+			Name string `json:"name"`
+		} `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpResourceSpans struct {
+		Resource struct {
+			Attributes []otlpAttribute `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+)
+
+const (
+	otlpStatusUnset = 0
+	otlpStatusError = 2
+	scopeName       = "adv-backend-trainee-assignment"
+)
+
+func otlpAttributeOf(key string, value interface{}) otlpAttribute {
+	var res otlpValue
+	switch v := value.(type) {
+	case string:
+		res.StringValue = &v
+	case int:
+		s := strconv.Itoa(v)
+		res.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		res.IntValue = &s
+	case float64:
+		res.DoubleValue = &v
+	case bool:
+		res.BoolValue = &v
+	default:
+		s := fmt.Sprint(v)
+		res.StringValue = &s
+	}
+	return otlpAttribute{Key: key, Value: res}
+}
+
+// marshalOTLP encodes spans as OTLP/JSON ExportTraceServiceRequest.
+func marshalOTLP(serviceName string, spans []*Span) ([]byte, error) {
+	scope := otlpScopeSpans{Spans: make([]otlpSpan, 0, len(spans))}
+	scope.Scope.Name = scopeName
+	for _, span := range spans {
+		span.mutex.Lock()
+		res := otlpSpan{
+			TraceID:           span.SpanContext.TraceID.String(),
+			SpanID:            span.SpanContext.SpanID.String(),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Status:            otlpStatus{Code: otlpStatusUnset},
+		}
+		if span.ParentSpanID.IsValid() {
+			res.ParentSpanID = span.ParentSpanID.String()
+		}
+		for key, value := range span.Attributes {
+			res.Attributes = append(res.Attributes, otlpAttributeOf(key, value))
+		}
+		if span.Failed {
+			res.Status = otlpStatus{Code: otlpStatusError, Message: span.Message}
+		}
+		span.mutex.Unlock()
+		scope.Spans = append(scope.Spans, res)
+	}
+	resource := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scope}}
+	resource.Resource.Attributes = []otlpAttribute{otlpAttributeOf("service.name", serviceName)}
+	return json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{resource}})
+}
+
+// WriterExporter writes every batch as a single line of OTLP/JSON, useful for stdout or a file.
+type WriterExporter struct {
+	writer io.Writer
+	closer io.Closer
+	mutex  sync.Mutex
+}
+
+func NewWriterExporter(writer io.Writer) *WriterExporter {
+	return &WriterExporter{writer: writer}
+}
+
+func NewFileExporter(path string) (*WriterExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &WriterExporter{writer: file, closer: file}, nil
+}
+
+func (exporter *WriterExporter) Export(serviceName string, spans []*Span) error {
+	body, err := marshalOTLP(serviceName, spans)
+	if err != nil {
+		return err
+	}
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	_, err = exporter.writer.Write(append(body, '\n'))
+	return err
+}
+
+func (exporter *WriterExporter) Close() error {
+	if exporter.closer == nil {
+		return nil
+	}
+	return exporter.closer.Close()
+}
+
+// OTLPExporter sends batches to OTLP/HTTP collector endpoint, e.g. http://localhost:4318/v1/traces.
+type OTLPExporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+func NewOTLPExporter(endpoint string, timeout time.Duration) *OTLPExporter {
+	return &OTLPExporter{endpoint: endpoint, client: &http.Client{Timeout: timeout}}
+}
+
+func (exporter *OTLPExporter) Export(serviceName string, spans []*Span) error {
+	body, err := marshalOTLP(serviceName, spans)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodPost, exporter.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := exporter.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("collector responded with %s", response.Status)
+	}
+	return nil
+}
+
+func (exporter *OTLPExporter) Close() error {
+	exporter.client.CloseIdleConnections()
+	return nil
+}
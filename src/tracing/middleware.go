@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"net/http"
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+// Middleware starts a server span per request, continuing the trace from incoming traceparent header.
+func (tracer *Tracer) Middleware(routeName string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if remote, ok := ParseTraceparent(r.Header.Get(TraceparentHeader)); ok {
+				ctx = ContextWithSpanContext(ctx, remote)
+			}
+			ctx, span := tracer.Start(ctx, routeName, SpanKindServer)
+			defer span.Finish()
+			span.SetAttribute("http.method", r.Method)
+			span.SetAttribute("http.target", r.URL.RequestURI())
+			span.SetAttribute("http.route", routeName)
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			h.ServeHTTP(recorder, r.WithContext(ctx))
+			span.SetAttribute("http.status_code", recorder.status)
+			if recorder.status >= http.StatusInternalServerError {
+				span.SetFailed(http.StatusText(recorder.status))
+			}
+		})
+	}
+}
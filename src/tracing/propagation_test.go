@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name            string
+		header          string
+		expectedOk      bool
+		expectedTraceID string
+		expectedSpanID  string
+		expectedSampled bool
+	}{
+		{
+			name:            "Sampled",
+			header:          "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expectedOk:      true,
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedSpanID:  "00f067aa0ba902b7",
+			expectedSampled: true,
+		},
+		{
+			name:            "Not sampled",
+			header:          "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			expectedOk:      true,
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedSpanID:  "00f067aa0ba902b7",
+		},
+		{
+			name:            "Future version with extra fields",
+			header:          "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			expectedOk:      true,
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedSpanID:  "00f067aa0ba902b7",
+			expectedSampled: true,
+		},
+		{
+			name:   "Version 00 with extra fields",
+			header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		},
+		{
+			name:   "Invalid version",
+			header: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+		{
+			name:   "Zero trace id",
+			header: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		},
+		{
+			name:   "Zero span id",
+			header: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		},
+		{
+			name:   "Not hex",
+			header: "00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+		},
+		{
+			name:   "Empty",
+			header: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := ParseTraceparent(tt.header)
+			assert.Equal(t, tt.expectedOk, ok)
+			if tt.expectedOk {
+				assert.Equal(t, tt.expectedTraceID, sc.TraceID.String())
+				assert.Equal(t, tt.expectedSpanID, sc.SpanID.String())
+				assert.Equal(t, tt.expectedSampled, sc.Sampled)
+			}
+		})
+	}
+}
+
+func TestFormatTraceparent(t *testing.T) {
+	header := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, ok := ParseTraceparent(header)
+	assert.True(t, ok)
+	assert.Equal(t, header, FormatTraceparent(sc))
+}
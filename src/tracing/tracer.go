@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	batchSize     = 512
+	queueSize     = 2048
+	flushInterval = 5 * time.Second
+)
+
+// Tracer starts spans and exports finished ones in batches from a background goroutine.
+type Tracer struct {
+	serviceName string
+	exporter    Exporter
+	queue       chan *Span
+	flush       chan chan struct{}
+	done        chan struct{}
+	mutex       sync.RWMutex
+	closed      bool
+}
+
+func NewTracer(serviceName string, exporter Exporter) *Tracer {
+	tracer := &Tracer{
+		serviceName: serviceName,
+		exporter:    exporter,
+		queue:       make(chan *Span, queueSize),
+		flush:       make(chan chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go tracer.run()
+	return tracer
+}
+
+// Start creates a child of the span in ctx, or a root span if there is none.
+func (tracer *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	span := &Span{
+		Name:       name,
+		Kind:       kind,
+		Start:      time.Now(),
+		Attributes: map[string]interface{}{},
+		tracer:     tracer,
+	}
+	if parent, ok := SpanContextFromContext(ctx); ok && parent.IsValid() {
+		span.SpanContext = SpanContext{TraceID: parent.TraceID, SpanID: newSpanID(), Sampled: parent.Sampled}
+		span.ParentSpanID = parent.SpanID
+	} else {
+		span.SpanContext = SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+	}
+	return ContextWithSpanContext(ctx, span.SpanContext), span
+}
+
+func (tracer *Tracer) enqueue(span *Span) {
+	tracer.mutex.RLock()
+	defer tracer.mutex.RUnlock()
+	if tracer.closed {
+		return
+	}
+	select {
+	case tracer.queue <- span:
+	default:
+		log.Warnf("tracing queue is full, dropping span %q", span.Name)
+	}
+}
+
+func (tracer *Tracer) run() {
+	defer close(tracer.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	batch := make([]*Span, 0, batchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := tracer.exporter.Export(tracer.serviceName, batch); err != nil {
+			log.Errorf("couldn't export %d spans. err: [%s]", len(batch), err)
+		}
+		batch = make([]*Span, 0, batchSize)
+	}
+	for {
+		select {
+		case span, ok := <-tracer.queue:
+			if !ok {
+				export()
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) >= batchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case flushed := <-tracer.flush:
+			for drained := false; !drained; {
+				select {
+				case span := <-tracer.queue:
+					batch = append(batch, span)
+				default:
+					drained = true
+				}
+			}
+			export()
+			close(flushed)
+		}
+	}
+}
+
+// Flush exports every span finished so far.
+func (tracer *Tracer) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case tracer.flush <- flushed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports remaining spans and releases the exporter. Spans finished after it are dropped.
+func (tracer *Tracer) Shutdown(ctx context.Context) error {
+	tracer.mutex.Lock()
+	if !tracer.closed {
+		tracer.closed = true
+		close(tracer.queue)
+	}
+	tracer.mutex.Unlock()
+	select {
+	case <-tracer.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return tracer.exporter.Close()
+}
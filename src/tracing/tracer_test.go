@@ -0,0 +1,168 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"adv-backend-trainee-assignment/src/db"
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingExporter struct {
+	mutex sync.Mutex
+	spans []*Span
+}
+
+func (exporter *recordingExporter) Export(serviceName string, spans []*Span) error {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	exporter.spans = append(exporter.spans, spans...)
+	return nil
+}
+
+func (exporter *recordingExporter) Close() error {
+	return nil
+}
+
+func (exporter *recordingExporter) byName(name string) *Span {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	for _, span := range exporter.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestTracer_Middleware(t *testing.T) {
+	tests := []struct {
+		name               string
+		traceparent        string
+		status             int
+		expectedTraceID    string
+		expectedParentID   string
+		expectedFailed     bool
+		expectedSpansCount int
+	}{
+		{
+			name:               "Continue incoming trace",
+			traceparent:        "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			status:             http.StatusOK,
+			expectedTraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedParentID:   "00f067aa0ba902b7",
+			expectedSpansCount: 2,
+		},
+		{
+			name:               "Start new trace",
+			status:             http.StatusGatewayTimeout,
+			expectedFailed:     true,
+			expectedSpansCount: 2,
+		},
+		{
+			name:               "Incoming trace is not sampled",
+			traceparent:        "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			status:             http.StatusOK,
+			expectedSpansCount: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := &recordingExporter{}
+			tracer := NewTracer("adv-api", exporter)
+			dbManager := db.Instrumented(db.NewMockedDBManager(), tracer.DBHook("mock"))
+			handler := tracer.Middleware("get ads")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = dbManager.CountAds(r.Context(), models.AdsFilter{})
+				w.WriteHeader(tt.status)
+			}))
+			request, err := http.NewRequest(http.MethodGet, "/ads?page=2", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.traceparent != "" {
+				request.Header.Set(TraceparentHeader, tt.traceparent)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), request)
+			assert.NoError(t, tracer.Shutdown(context.Background()))
+			assert.Equal(t, tt.expectedSpansCount, len(exporter.spans))
+			if tt.expectedSpansCount == 0 {
+				return
+			}
+			server, query := exporter.byName("get ads"), exporter.byName("db.CountAds")
+			if server == nil || query == nil {
+				t.Fatalf("missing spans: %v", exporter.spans)
+			}
+			assert.Equal(t, SpanKindServer, server.Kind)
+			assert.Equal(t, SpanKindClient, query.Kind)
+			assert.Equal(t, server.SpanContext.TraceID, query.SpanContext.TraceID)
+			assert.Equal(t, server.SpanContext.SpanID, query.ParentSpanID)
+			if tt.expectedTraceID != "" {
+				assert.Equal(t, tt.expectedTraceID, server.SpanContext.TraceID.String())
+				assert.Equal(t, tt.expectedParentID, server.ParentSpanID.String())
+			} else {
+				assert.False(t, server.ParentSpanID.IsValid())
+			}
+			assert.Equal(t, tt.expectedFailed, server.Failed)
+			assert.Equal(t, "/ads?page=2", server.Attributes["http.target"])
+			assert.Equal(t, tt.status, server.Attributes["http.status_code"])
+			assert.Equal(t, "mock", query.Attributes["db.system"])
+		})
+	}
+}
+
+func TestWriterExporter(t *testing.T) {
+	var buffer bytes.Buffer
+	tracer := NewTracer("adv-api", NewWriterExporter(&buffer))
+	ctx, parent := tracer.Start(context.Background(), "parent", SpanKindServer)
+	_, child := tracer.Start(ctx, "child", SpanKindInternal)
+	child.SetAttribute("count", 3)
+	child.RecordError(errors.New("boom"))
+	child.Finish()
+	parent.Finish()
+	parent.Finish()
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+
+	var exported otlpRequest
+	if err := json.Unmarshal(buffer.Bytes(), &exported); err != nil {
+		t.Fatalf("unexpected output: %v", err)
+	}
+	assert.Equal(t, 1, len(exported.ResourceSpans))
+	assert.Equal(t, "adv-api", *exported.ResourceSpans[0].Resource.Attributes[0].Value.StringValue)
+	spans := exported.ResourceSpans[0].ScopeSpans[0].Spans
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, parent.SpanContext.SpanID.String(), spans[0].ParentSpanID)
+	assert.Equal(t, otlpStatusError, spans[0].Status.Code)
+	assert.Equal(t, "boom", spans[0].Status.Message)
+	assert.Equal(t, "3", *spans[0].Attributes[0].Value.IntValue)
+	assert.Equal(t, "", spans[1].ParentSpanID)
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var received []byte
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		received, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer collector.Close()
+	tracer := NewTracer("adv-api", NewOTLPExporter(collector.URL, 0))
+	_, span := tracer.Start(context.Background(), "span", SpanKindInternal)
+	span.Finish()
+	assert.NoError(t, tracer.Flush(context.Background()))
+	assert.Contains(t, string(received), span.SpanContext.TraceID.String())
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	assert.Error(t, NewOTLPExporter(failing.URL, 0).Export("adv-api", []*Span{span}))
+}
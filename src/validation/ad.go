@@ -0,0 +1,94 @@
+package validation
+
+import "adv-backend-trainee-assignment/src/models"
+
+// AdLimits holds the product limits for ads. Zero fields fall back to DefaultAdLimits.
+type AdLimits struct {
+	TitleMaxLength       int
+	DescriptionMaxLength int
+	PhotoLinksMax        int
+	PriceMax             int64
+}
+
+var DefaultAdLimits = AdLimits{
+	TitleMaxLength:       200,
+	DescriptionMaxLength: 1000,
+	PhotoLinksMax:        3,
+	PriceMax:             1000000000,
+}
+
+func (l AdLimits) withDefaults() AdLimits {
+	if l.TitleMaxLength <= 0 {
+		l.TitleMaxLength = DefaultAdLimits.TitleMaxLength
+	}
+	if l.DescriptionMaxLength <= 0 {
+		l.DescriptionMaxLength = DefaultAdLimits.DescriptionMaxLength
+	}
+	if l.PhotoLinksMax <= 0 {
+		l.PhotoLinksMax = DefaultAdLimits.PhotoLinksMax
+	}
+	if l.PriceMax <= 0 {
+		l.PriceMax = DefaultAdLimits.PriceMax
+	}
+	return l
+}
+
+type adField struct {
+	name  string
+	rules []Rule
+}
+
+func (l AdLimits) fields() []adField {
+	l = l.withDefaults()
+	return []adField{
+		{name: "title", rules: []Rule{RuneLength(1, l.TitleMaxLength)}},
+		{name: "description", rules: []Rule{RuneLength(1, l.DescriptionMaxLength)}},
+		{name: "photoLinks", rules: []Rule{Count(1, l.PhotoLinksMax), URLs("http", "https"), Unique()}},
+		{name: "price", rules: []Rule{Range(1, l.PriceMax)}},
+		{name: "categoryID", rules: []Rule{Required()}},
+	}
+}
+
+func (l AdLimits) ValidateAd(ad models.CreatingAd) []models.FieldError {
+	values := map[string]interface{}{
+		"title":       ad.Title,
+		"description": ad.Description,
+		"photoLinks":  ad.PhotoLinks,
+		"price":       ad.Price,
+		"categoryID":  ad.CategoryID,
+	}
+	var fieldErrors []models.FieldError
+	for _, field := range l.fields() {
+		fieldErrors = append(fieldErrors, Field(field.name, values[field.name], field.rules...)...)
+	}
+	return fieldErrors
+}
+
+// ValidateUpdatingAd checks only the given fields, unless fullReplace requires all of them.
+func (l AdLimits) ValidateUpdatingAd(ad models.UpdatingAd, fullReplace bool) []models.FieldError {
+	values := make(map[string]interface{})
+	if ad.Title != nil {
+		values["title"] = *ad.Title
+	}
+	if ad.Description != nil {
+		values["description"] = *ad.Description
+	}
+	if ad.PhotoLinks != nil {
+		values["photoLinks"] = *ad.PhotoLinks
+	}
+	if ad.Price != nil {
+		values["price"] = *ad.Price
+	}
+	if ad.CategoryID != nil {
+		values["categoryID"] = *ad.CategoryID
+	}
+	var fieldErrors []models.FieldError
+	for _, field := range l.fields() {
+		if value, ok := values[field.name]; ok {
+			fieldErrors = append(fieldErrors, Field(field.name, value, field.rules...)...)
+		} else if fullReplace {
+			fieldErrors = append(fieldErrors, Field(field.name, "", Required())...)
+		}
+	}
+	return fieldErrors
+}
@@ -0,0 +1,96 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func fieldNames(fieldErrors []models.FieldError) []string {
+	names := make([]string, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		names = append(names, fieldError.Field)
+	}
+	return names
+}
+
+func TestAdLimits_ValidateAd(t *testing.T) {
+	validAd := models.CreatingAd{
+		Title:       "Велосипед",
+		Description: "Почти новый",
+		PhotoLinks:  []string{"https://ya.ru/1.png"},
+		Price:       100,
+		CategoryID:  "22e88a53-3c80-429d-9e84-99d217788098",
+	}
+	tests := []struct {
+		name           string
+		limits         AdLimits
+		ad             func(ad models.CreatingAd) models.CreatingAd
+		expectedFields []string
+	}{
+		{
+			name:           "Valid ad",
+			ad:             func(ad models.CreatingAd) models.CreatingAd { return ad },
+			expectedFields: []string{},
+		},
+		{
+			name: "Cyrillic title of 200 characters",
+			ad: func(ad models.CreatingAd) models.CreatingAd {
+				ad.Title = strings.Repeat("ж", 200)
+				return ad
+			},
+			expectedFields: []string{},
+		},
+		{
+			name: "All violations at once",
+			ad: func(ad models.CreatingAd) models.CreatingAd {
+				return models.CreatingAd{PhotoLinks: []string{"javascript:alert(1)"}, Price: 1000000001}
+			},
+			expectedFields: []string{"title", "description", "photoLinks[0]", "price", "categoryID"},
+		},
+		{
+			name:   "Limits from config",
+			limits: AdLimits{TitleMaxLength: 5, PriceMax: 50},
+			ad: func(ad models.CreatingAd) models.CreatingAd {
+				ad.Title = "Велосипед"
+				return ad
+			},
+			expectedFields: []string{"title", "price"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedFields, fieldNames(tt.limits.ValidateAd(tt.ad(validAd))))
+		})
+	}
+}
+
+func TestAdLimits_ValidateUpdatingAd(t *testing.T) {
+	title := ""
+	price := int64(10)
+	tests := []struct {
+		name           string
+		ad             models.UpdatingAd
+		fullReplace    bool
+		expectedFields []string
+	}{
+		{
+			name:           "Patch checks only given fields",
+			ad:             models.UpdatingAd{Title: &title, Price: &price},
+			expectedFields: []string{"title"},
+		},
+		{
+			name:           "Put requires all fields",
+			ad:             models.UpdatingAd{Price: &price},
+			fullReplace:    true,
+			expectedFields: []string{"title", "description", "photoLinks", "categoryID"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedFields, fieldNames(AdLimits{}.ValidateUpdatingAd(tt.ad, tt.fullReplace)))
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"adv-backend-trainee-assignment/src/models"
+)
+
+const (
+	CodeRequired   = "required"
+	CodeTooShort   = "too_short"
+	CodeTooLong    = "too_long"
+	CodeTooFew     = "too_few"
+	CodeTooMany    = "too_many"
+	CodeOutOfRange = "out_of_range"
+	CodeInvalid    = "invalid"
+	CodeDuplicate  = "duplicate"
+)
+
+// Violation is a single failed rule. Path is appended to the field name, e.g. "[1]" for a list item.
+type Violation struct {
+	Path    string
+	Code    string
+	Message string
+}
+
+// Rule checks a value of the type it was built for and panics on any other type.
+type Rule func(value interface{}) []Violation
+
+// Field runs every rule against value, so all violations are reported at once.
+func Field(name string, value interface{}, rules ...Rule) []models.FieldError {
+	var fieldErrors []models.FieldError
+	for _, rule := range rules {
+		for _, violation := range rule(value) {
+			fieldErrors = append(fieldErrors, models.FieldError{
+				Field:   name + violation.Path,
+				Code:    violation.Code,
+				Message: name + violation.Path + " " + violation.Message,
+			})
+		}
+	}
+	return fieldErrors
+}
+
+func Required() Rule {
+	return func(value interface{}) []Violation {
+		if value.(string) == "" {
+			return []Violation{{Code: CodeRequired, Message: "should be given"}}
+		}
+		return nil
+	}
+}
+
+// RuneLength counts characters rather than bytes, so Cyrillic text gets the same limits as Latin.
+func RuneLength(min, max int) Rule {
+	return func(value interface{}) []Violation {
+		length := utf8.RuneCountInString(value.(string))
+		if length == 0 && min > 0 {
+			return []Violation{{Code: CodeRequired, Message: "should not be empty"}}
+		} else if length < min {
+			return []Violation{{Code: CodeTooShort, Message: fmt.Sprintf("should be at least %d characters long", min)}}
+		} else if length > max {
+			return []Violation{{Code: CodeTooLong, Message: fmt.Sprintf("should be at most %d characters long", max)}}
+		}
+		return nil
+	}
+}
+
+func Count(min, max int) Rule {
+	return func(value interface{}) []Violation {
+		count := len(value.([]string))
+		if count < min {
+			return []Violation{{Code: CodeTooFew, Message: fmt.Sprintf("should have from %d to %d items", min, max)}}
+		} else if count > max {
+			return []Violation{{Code: CodeTooMany, Message: fmt.Sprintf("should have from %d to %d items", min, max)}}
+		}
+		return nil
+	}
+}
+
+func Range(min, max int64) Rule {
+	return func(value interface{}) []Violation {
+		number := value.(int64)
+		if number < min || number > max {
+			return []Violation{{Code: CodeOutOfRange, Message: fmt.Sprintf("should be from %d to %d", min, max)}}
+		}
+		return nil
+	}
+}
+
+// URLs checks that every item is an absolute URL with a host and one of the given schemes.
+func URLs(schemes ...string) Rule {
+	return func(value interface{}) []Violation {
+		var violations []Violation
+		for i, item := range value.([]string) {
+			if !validURL(item, schemes) {
+				violations = append(violations, Violation{
+					Path:    fmt.Sprintf("[%d]", i),
+					Code:    CodeInvalid,
+					Message: "should be a URL with scheme " + strings.Join(schemes, " or "),
+				})
+			}
+		}
+		return violations
+	}
+}
+
+func validURL(rawURL string, schemes []string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	for _, scheme := range schemes {
+		if parsed.Scheme == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+func Unique() Rule {
+	return func(value interface{}) []Violation {
+		var violations []Violation
+		seen := make(map[string]int)
+		for i, item := range value.([]string) {
+			if first, ok := seen[item]; ok {
+				violations = append(violations, Violation{
+					Path:    fmt.Sprintf("[%d]", i),
+					Code:    CodeDuplicate,
+					Message: fmt.Sprintf("duplicates item %d", first),
+				})
+			} else {
+				seen[item] = i
+			}
+		}
+		return violations
+	}
+}
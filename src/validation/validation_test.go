@@ -0,0 +1,88 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"adv-backend-trainee-assignment/src/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestField(t *testing.T) {
+	tests := []struct {
+		name           string
+		value          interface{}
+		rules          []Rule
+		expectedErrors []models.FieldError
+	}{
+		{
+			name:  "Cyrillic text is measured in characters",
+			value: strings.Repeat("я", 200),
+			rules: []Rule{RuneLength(1, 200)},
+		},
+		{
+			name:  "Too long text",
+			value: strings.Repeat("я", 201),
+			rules: []Rule{RuneLength(1, 200)},
+			expectedErrors: []models.FieldError{
+				{Field: "title", Code: CodeTooLong, Message: "title should be at most 200 characters long"},
+			},
+		},
+		{
+			name:  "Empty text",
+			value: "",
+			rules: []Rule{RuneLength(1, 200)},
+			expectedErrors: []models.FieldError{
+				{Field: "title", Code: CodeRequired, Message: "title should not be empty"},
+			},
+		},
+		{
+			name:  "Too short text",
+			value: "ab",
+			rules: []Rule{RuneLength(3, 200)},
+			expectedErrors: []models.FieldError{
+				{Field: "title", Code: CodeTooShort, Message: "title should be at least 3 characters long"},
+			},
+		},
+		{
+			name:  "Required value",
+			value: "",
+			rules: []Rule{Required()},
+			expectedErrors: []models.FieldError{
+				{Field: "title", Code: CodeRequired, Message: "title should be given"},
+			},
+		},
+		{
+			name:  "Number out of range",
+			value: int64(1001),
+			rules: []Rule{Range(1, 1000)},
+			expectedErrors: []models.FieldError{
+				{Field: "title", Code: CodeOutOfRange, Message: "title should be from 1 to 1000"},
+			},
+		},
+		{
+			name:  "All violations of a list",
+			value: []string{"https://ya.ru", "ftp://ya.ru", "ya.ru", "https://ya.ru"},
+			rules: []Rule{Count(1, 3), URLs("http", "https"), Unique()},
+			expectedErrors: []models.FieldError{
+				{Field: "title", Code: CodeTooMany, Message: "title should have from 1 to 3 items"},
+				{Field: "title[1]", Code: CodeInvalid, Message: "title[1] should be a URL with scheme http or https"},
+				{Field: "title[2]", Code: CodeInvalid, Message: "title[2] should be a URL with scheme http or https"},
+				{Field: "title[3]", Code: CodeDuplicate, Message: "title[3] duplicates item 0"},
+			},
+		},
+		{
+			name:  "Empty list",
+			value: []string{},
+			rules: []Rule{Count(1, 3), URLs("http", "https"), Unique()},
+			expectedErrors: []models.FieldError{
+				{Field: "title", Code: CodeTooFew, Message: "title should have from 1 to 3 items"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedErrors, Field("title", tt.value, tt.rules...))
+		})
+	}
+}